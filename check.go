@@ -0,0 +1,340 @@
+package esql
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DriftAdded means the column exists in the table but not in the model (表中新增的字段)
+	DriftAdded = "added"
+	// DriftRemoved means the column exists in the model but not in the table (表中已删除的字段)
+	DriftRemoved = "removed"
+	// DriftTypeChanged means the Go type of the column has changed (字段类型已改变)
+	DriftTypeChanged = "type changed"
+)
+
+// Drift describes the difference between a generated model file and the current schema.
+// (模型文件与当前表结构的差异)
+type Drift struct {
+	File string
+	// 文件需要生成但不存在
+	Missing bool
+	// 文件是生成的，其中的表在过滤规则内但已不存在
+	Stale   bool
+	Changes []FieldDrift
+}
+
+// FieldDrift describes a changed field of a model struct (结构体字段的差异)
+type FieldDrift struct {
+	Struct string
+	Field  string
+	Column string
+	Kind   string
+	// 模型中的类型
+	OldType string
+	// 当前表结构对应的类型
+	NewType string
+}
+
+func (d Drift) String() string {
+	switch {
+	case d.Missing:
+		return fmt.Sprintf("%s: missing", d.File)
+	case d.Stale:
+		return fmt.Sprintf("%s: table no longer exists", d.File)
+	}
+
+	lines := make([]string, 0, len(d.Changes)+1)
+	lines = append(lines, d.File+":")
+	for _, c := range d.Changes {
+		switch c.Kind {
+		case DriftAdded:
+			lines = append(lines, fmt.Sprintf("  + %s.%s (%s) %s", c.Struct, c.Field, c.Column, c.NewType))
+		case DriftRemoved:
+			lines = append(lines, fmt.Sprintf("  - %s.%s (%s) %s", c.Struct, c.Field, c.Column, c.OldType))
+		default:
+			lines = append(lines, fmt.Sprintf("  ~ %s.%s (%s) %s -> %s", c.Struct, c.Field, c.Column, c.OldType, c.NewType))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// CheckStructByTable compares the model files in savePath with what GenStructByTable would generate now.
+// An empty result means the models are up to date.
+// (检查savePath中的模型文件与当前表结构是否一致，结果为空表示一致)
+func CheckStructByTable(mode, dsn, dbName, savePath string, hasTag bool, opts ...GenOption) ([]Drift, error) {
+	db, err := Open(mode, dsn, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer db.db.Close()
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.CheckStructByTable(mode, dbName, savePath, hasTag, opts...)
+}
+
+// CheckStructByTable compares the model files in savePath with what GenStructByTable would generate now.
+// (检查savePath中的模型文件与当前表结构是否一致)
+func (e *DB) CheckStructByTable(mode, dbName, savePath string, hasTag bool, opts ...GenOption) ([]Drift, error) {
+	opt := newGenOptions(opts...)
+	return checkDrift(savePath, opt, func() ([]Table, error) {
+		return e.allTables(mode, dbName, opt)
+	}, func(tmpPath string) error {
+		return e.GenStructByTable(mode, dbName, tmpPath, hasTag, opts...)
+	})
+}
+
+// CheckStructByDDL compares the model files in savePath with what GenStructByDDL would generate now.
+// (检查savePath中的模型文件与DDL文件是否一致)
+func CheckStructByDDL(mode, ddl, savePath string, hasTag bool, opts ...GenOption) ([]Drift, error) {
+	opt := newGenOptions(opts...)
+	return checkDrift(savePath, opt, func() ([]Table, error) {
+		return allTablesByDDL(mode, ddl, opt)
+	}, func(tmpPath string) error {
+		return GenStructByDDL(mode, ddl, tmpPath, hasTag, opts...)
+	})
+}
+
+// 生成到临时目录，再与已有文件比较，allTables为未过滤的所有表，用于判断没有重新生成的文件中的表是否已删除
+func checkDrift(savePath string, opt *genOptions, allTables func() ([]Table, error), gen func(tmpPath string) error) ([]Drift, error) {
+	if savePath == "" {
+		savePath = "."
+	}
+	savePath, err := filepath.Abs(savePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "esql-check-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	// 目录名与savePath一致，保证生成的包名相同
+	tmpPath := filepath.Join(tmp, filepath.Base(savePath))
+	if err = os.Mkdir(tmpPath, 0755); err != nil {
+		return nil, err
+	}
+	if err = gen(filepath.ToSlash(tmpPath)); err != nil {
+		return nil, err
+	}
+
+	generated, err := filepath.Glob(filepath.Join(tmpPath, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := make([]Drift, 0)
+	names := make(map[string]bool, len(generated))
+	for _, file := range generated {
+		name := filepath.Base(file)
+		names[name] = true
+
+		want, _, err := parseStructs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		existing := filepath.Join(savePath, name)
+		if _, err := os.Stat(existing); os.IsNotExist(err) {
+			drifts = append(drifts, Drift{File: name, Missing: true})
+			continue
+		}
+
+		got, _, err := parseStructs(existing)
+		if err != nil {
+			return nil, err
+		}
+
+		if changes := diffStructs(got, want); len(changes) > 0 {
+			drifts = append(drifts, Drift{File: name, Changes: changes})
+		}
+	}
+
+	// 没有重新生成的文件，只有生成的、表在过滤规则内且已不存在的才是过期的
+	files, err := filepath.Glob(filepath.Join(savePath, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var existing map[string]bool
+	for _, file := range files {
+		name := filepath.Base(file)
+		if names[name] {
+			continue
+		}
+
+		_, table, err := parseStructs(file)
+		if err != nil {
+			return nil, err
+		}
+		if table == "" {
+			continue
+		}
+		ok, err := opt.includeTable(table)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if !isGenerated(src) && !isLegacyGenerated(src) {
+			continue
+		}
+
+		if existing == nil {
+			tables, err := allTables()
+			if err != nil {
+				return nil, err
+			}
+			existing = make(map[string]bool, len(tables))
+			for _, t := range tables {
+				existing[t.Name] = true
+			}
+		}
+		if !existing[table] {
+			drifts = append(drifts, Drift{File: name, Stale: true})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].File < drifts[j].File
+	})
+
+	return drifts, nil
+}
+
+// 结构体字段
+type structField struct {
+	Name   string
+	Column string
+	Type   string
+}
+
+// 解析文件中的结构体，返回结构体字段和TableName方法返回的表名，没有时为空
+func parseStructs(filename string) (map[string][]structField, string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), filename, nil, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var table string
+	structs := make(map[string][]structField)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && d.Name.Name == "TableName" && table == "" {
+				table = returnedString(d)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+
+				fields := make([]structField, 0, len(st.Fields.List))
+				for _, field := range st.Fields.List {
+					typ := types.ExprString(field.Type)
+					for _, name := range field.Names {
+						fields = append(fields, structField{Name: name.Name, Column: fieldColumn(name.Name, field.Tag), Type: typ})
+					}
+				}
+				structs[ts.Name.Name] = fields
+			}
+		}
+	}
+
+	return structs, table, nil
+}
+
+// 方法中返回的字符串常量
+func returnedString(fn *ast.FuncDecl) string {
+	if fn.Body == nil || len(fn.Body.List) != 1 {
+		return ""
+	}
+	ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return ""
+	}
+	lit, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+
+	value, _ := strconv.Unquote(lit.Value)
+	return value
+}
+
+// 字段对应的列名，没有标签时为下划线格式
+func fieldColumn(name string, tag *ast.BasicLit) string {
+	if tag != nil {
+		value, _ := strconv.Unquote(tag.Value)
+		if column := reflect.StructTag(value).Get(tagName); column != "" {
+			return strings.Split(column, ",")[0]
+		}
+	}
+
+	return ConvertCamelToSnake(name)
+}
+
+// 按列名比较结构体字段，got是已有的，want是重新生成的，字段名的变化（如缩略词不同）不算差异
+func diffStructs(got, want map[string][]structField) []FieldDrift {
+	names := make([]string, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []FieldDrift
+	for _, name := range names {
+		old := make(map[string]structField, len(got[name]))
+		for _, f := range got[name] {
+			old[f.Column] = f
+		}
+
+		current := make(map[string]bool, len(want[name]))
+		for _, f := range want[name] {
+			current[f.Column] = true
+			o, ok := old[f.Column]
+			switch {
+			case !ok:
+				changes = append(changes, FieldDrift{Struct: name, Field: f.Name, Column: f.Column, Kind: DriftAdded, NewType: f.Type})
+			case o.Type != f.Type:
+				changes = append(changes, FieldDrift{Struct: name, Field: f.Name, Column: f.Column, Kind: DriftTypeChanged, OldType: o.Type, NewType: f.Type})
+			}
+		}
+
+		for _, f := range got[name] {
+			if !current[f.Column] {
+				changes = append(changes, FieldDrift{Struct: name, Field: f.Name, Column: f.Column, Kind: DriftRemoved, OldType: f.Type})
+			}
+		}
+	}
+
+	return changes
+}
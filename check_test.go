@@ -0,0 +1,94 @@
+package esql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 写入DDL文件，返回文件路径
+func writeDDL(t *testing.T, dir, ddl string) string {
+	t.Helper()
+
+	filename := filepath.Join(dir, "schema.sql")
+	if err := os.WriteFile(filename, []byte(ddl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestCheckStructByDDL(t *testing.T) {
+	dir := t.TempDir()
+	savePath := filepath.Join(dir, "model")
+	if err := os.Mkdir(savePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ddl := writeDDL(t, dir, `create table account (id bigint primary key, name varchar(32) not null, age int not null, sku_id int not null);
+create table audit (id bigint primary key);
+create table draft (id bigint primary key);`)
+	if err := GenStructByDDL(Mysql, ddl, savePath, false); err != nil {
+		t.Fatal(err)
+	}
+	// 手写的带TableName的文件不检查
+	handwritten := "package model\n\ntype Legacy struct{}\n\nfunc (Legacy) TableName() string {\n\treturn \"legacy\"\n}\n"
+	if err := os.WriteFile(filepath.Join(savePath, "legacy.go"), []byte(handwritten), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	drifts, err := CheckStructByDDL(Mysql, ddl, savePath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("expected no drift, got %v", drifts)
+	}
+
+	// 字段名因缩略词改变，列名不变时不算差异
+	drifts, err = CheckStructByDDL(Mysql, ddl, savePath, false, WithInitialisms("SKU"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("expected no drift after changing initialisms, got %v", drifts)
+	}
+
+	// 新增email、删除name、age类型改变、draft表删除、新增tag表
+	ddl = writeDDL(t, dir, `create table account (id bigint primary key, age bigint not null, sku_id int not null, email varchar(64) not null);
+create table audit (id bigint primary key);
+create table tag (id int primary key);`)
+	drifts, err = CheckStructByDDL(Mysql, ddl, savePath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Drift{
+		{File: "account.go", Changes: []FieldDrift{
+			{Struct: "Account", Field: "Age", Column: "age", Kind: DriftTypeChanged, OldType: "int32", NewType: "int64"},
+			{Struct: "Account", Field: "Email", Column: "email", Kind: DriftAdded, NewType: "string"},
+			{Struct: "Account", Field: "Name", Column: "name", Kind: DriftRemoved, OldType: "string"},
+		}},
+		{File: "draft.go", Stale: true},
+		{File: "tag.go", Missing: true},
+	}
+	if !reflect.DeepEqual(drifts, want) {
+		t.Fatalf("got  %+v\nwant %+v", drifts, want)
+	}
+
+	// 只检查过滤后的表，其他表的文件不报告为过期
+	drifts, err = CheckStructByDDL(Mysql, ddl, savePath, false, WithTables("audit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("expected no drift for audit, got %v", drifts)
+	}
+	drifts, err = CheckStructByDDL(Mysql, ddl, savePath, false, WithTables("a*", "draft"), WithExcludeTables("account"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(drifts, []Drift{{File: "draft.go", Stale: true}}) {
+		t.Fatalf("unexpected drifts: %+v", drifts)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cyj19/esql"
+	"gopkg.in/yaml.v3"
+)
+
+// 默认的配置文件
+const defaultConfigFile = "esql.yaml"
+
+// 覆盖配置文件中密码的环境变量
+const passwordEnv = "ESQL_PASSWORD"
+
+// esql.yaml的内容，命令行参数优先于配置文件
+type config struct {
+	Mode       string   `yaml:"mode"`
+	DSN        string   `yaml:"dsn"`
+	Host       string   `yaml:"host"`
+	Port       int      `yaml:"port"`
+	User       string   `yaml:"user"`
+	Password   string   `yaml:"password"`
+	DB         string   `yaml:"db"`
+	File       string   `yaml:"file"`
+	Schema     string   `yaml:"schema"`
+	DDL        string   `yaml:"ddl"`
+	Path       string   `yaml:"path"`
+	Package    string   `yaml:"package"`
+	TrimPrefix []string `yaml:"trimprefix"`
+	Filename   string   `yaml:"filename"`
+	Force      bool     `yaml:"force"`
+	// 在默认缩略词之外额外保持大写的缩略词
+	Initialisms []string `yaml:"initialisms"`
+	Tag         bool     `yaml:"tag"`
+	Tags        []string `yaml:"tags"`
+	TagCase     string   `yaml:"tagcase"`
+	Null        string   `yaml:"null"`
+	Decimal     string   `yaml:"decimal"`
+	TypeMap     string   `yaml:"typemap"`
+	// 直接写在配置文件中的类型映射，优先于typemap文件
+	Types    map[string]string `yaml:"types"`
+	Columns  map[string]string `yaml:"columns"`
+	Template string            `yaml:"template"`
+	CRUD     bool              `yaml:"crud"`
+	Tables   []string          `yaml:"tables"`
+	Exclude  []string          `yaml:"exclude"`
+}
+
+// YAML把null键解析为空值，按字符串处理，null: ptr才能对应Null字段
+func (c *config) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.ShortTag() == "!!null" {
+				key.Tag = "!!str"
+			}
+		}
+	}
+
+	type plain config
+	return node.Decode((*plain)(c))
+}
+
+func defaultConfig() config {
+	return config{
+		Mode:    esql.Mysql,
+		Host:    "127.0.0.1",
+		User:    "root",
+		Schema:  "public",
+		Path:    "./",
+		Decimal: "string",
+	}
+}
+
+// 逗号分隔的列表参数，patterns为true时是表名模式，/regexp/中的逗号不作为分隔符
+type listValue struct {
+	list     *[]string
+	patterns bool
+}
+
+func (v listValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+func (v listValue) Set(s string) error {
+	items := strings.Split(s, ",")
+	if v.patterns {
+		items = splitPatterns(s)
+	}
+
+	*v.list = nil
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			*v.list = append(*v.list, item)
+		}
+	}
+	return nil
+}
+
+// 按逗号分隔表名模式，以/开头的正则表达式到后面紧跟逗号或结尾的/为止，如/^t_\d{1,2}$/,user
+func splitPatterns(s string) []string {
+	var out []string
+	start, open := 0, -1
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '/' && open < 0 && strings.TrimSpace(s[start:i]) == "":
+			open = i
+		case s[i] == '/' && open >= 0 && i > open:
+			if rest := strings.TrimSpace(s[i+1:]); rest == "" || rest[0] == ',' {
+				open = -1
+			}
+		case s[i] == ',' && open < 0:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(out, s[start:])
+}
+
+// 注册命令行参数
+func bindFlags(fs *flag.FlagSet, c *config) {
+	fs.StringVar(&c.Mode, "mode", c.Mode, "the database drive: mysql, postgres or sqlite3")
+	fs.StringVar(&c.DSN, "dsn", c.DSN, "the dataSource")
+	fs.StringVar(&c.Host, "ip", c.Host, "the database ip")
+	fs.IntVar(&c.Port, "port", c.Port, "the database port, default is 3306 for mysql and 5432 for postgres")
+	fs.StringVar(&c.User, "u", c.User, "the database user")
+	fs.StringVar(&c.Password, "p", c.Password, "the database password, $"+passwordEnv+" is used if not set")
+	fs.StringVar(&c.DB, "db", c.DB, "the database name")
+	fs.StringVar(&c.File, "file", c.File, "the database file for sqlite3")
+	fs.StringVar(&c.Schema, "schema", c.Schema, "the postgres schema")
+	fs.StringVar(&c.DDL, "ddl", c.DDL, "read the sql file or directory of CREATE TABLE statements instead of a database")
+	fs.StringVar(&c.Path, "path", c.Path, "the path to save file")
+	fs.StringVar(&c.Package, "package", c.Package, "the package name, default is the last segment of path")
+	fs.Var(listValue{list: &c.TrimPrefix}, "trimprefix", "the table prefixes to strip separated by commas, e.g. t_")
+	fs.StringVar(&c.Filename, "filename", c.Filename, "the file name pattern, {table} is replaced by the table name (default \"{table}.go\")")
+	fs.BoolVar(&c.Force, "force", c.Force, "overwrite files without the \"// Code generated ... DO NOT EDIT.\" header")
+	fs.Var(listValue{list: &c.Initialisms}, "initialisms", "the extra initialisms kept in upper case separated by commas, e.g. SKU,VIP")
+	fs.BoolVar(&c.Tag, "tag", c.Tag, "the generated structure needs to be tagged")
+	fs.Var(listValue{list: &c.Tags}, "tags", "the extra struct tags separated by commas, e.g. json,yaml,form")
+	fs.StringVar(&c.TagCase, "tagcase", c.TagCase, "the naming of the extra struct tags: snake, camel or original")
+	fs.StringVar(&c.Null, "null", c.Null, "the type of nullable columns: sql (sql.Null*) or ptr (pointer)")
+	fs.StringVar(&c.Decimal, "decimal", c.Decimal, "the type of decimal columns, e.g. github.com/shopspring/decimal.Decimal")
+	fs.StringVar(&c.TypeMap, "typemap", c.TypeMap, "the YAML (.yaml, .yml) or JSON (.json) file of type mapping rules")
+	fs.StringVar(&c.Template, "template", c.Template, "the template file, or the directory of *.tpl files")
+	fs.BoolVar(&c.CRUD, "crud", c.CRUD, "also generate the model with CRUD methods")
+	fs.Var(listValue{list: &c.Tables, patterns: true}, "tables", "the tables to generate separated by commas, supports globs and /regexp/ (commas in /regexp/ are kept)")
+	fs.Var(listValue{list: &c.Exclude, patterns: true}, "exclude", "the tables to skip separated by commas, supports globs and /regexp/ (commas in /regexp/ are kept)")
+}
+
+// 解析子命令的参数，依次使用默认值、配置文件、环境变量和命令行参数
+func parseConfig(name string, args []string) (*config, error) {
+	fs := flag.NewFlagSet("esql "+name, flag.ExitOnError)
+	configFile := fs.String("config", defaultConfigFile, "the config file")
+	flags := defaultConfig()
+	bindFlags(fs, &flags)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: esql %s [flags]\n\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 未指定配置文件时，默认的配置文件可以不存在
+	explicit := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicit = true
+		}
+	})
+
+	c := defaultConfig()
+	data, err := os.ReadFile(*configFile)
+	switch {
+	case err == nil:
+		if err = yaml.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", *configFile, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		err = nil
+	default:
+		return nil, err
+	}
+
+	if password, ok := os.LookupEnv(passwordEnv); ok {
+		c.Password = password
+	}
+
+	// 只覆盖命令行中指定的参数
+	target := flag.NewFlagSet(name, flag.ContinueOnError)
+	bindFlags(target, &c)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" && err == nil {
+			err = target.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// 检查连接参数是否完整
+func (c *config) validate() error {
+	if c.DDL != "" {
+		return nil
+	}
+
+	switch c.Mode {
+	case esql.Mysql:
+		if c.DB == "" {
+			return errors.New("the database name is required, use -db")
+		}
+	case esql.Postgres:
+		if c.DB == "" && c.DSN == "" {
+			return errors.New("the database name is required, use -db or -dsn")
+		}
+	case esql.SQLite:
+		if c.File == "" && c.DB == "" && c.DSN == "" {
+			return errors.New("the database file is required, use -file")
+		}
+	default:
+		return fmt.Errorf("unsupported mode: %s", c.Mode)
+	}
+
+	return nil
+}
+
+// 数据库连接字符串
+func (c *config) dataSource() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+
+	switch c.Mode {
+	case esql.Mysql:
+		port := c.Port
+		if port == 0 {
+			port = 3306
+		}
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8", c.User, c.Password, c.Host, port, c.DB)
+	case esql.Postgres:
+		port := c.Port
+		if port == 0 {
+			port = 5432
+		}
+		return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
+			pqQuote(c.Host), pqQuote(c.User), pqQuote(c.Password), pqQuote(c.DB), port)
+	case esql.SQLite:
+		if c.File != "" {
+			return c.File
+		}
+		return c.DB
+	}
+
+	return ""
+}
+
+// 按libpq的规则引用连接参数中的值
+func pqQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, ` '\`) {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// 生成选项
+func (c *config) options() ([]esql.GenOption, error) {
+	var nullable esql.NullableMode
+	switch c.Null {
+	case "":
+		nullable = esql.NullableNone
+	case "sql":
+		nullable = esql.NullableSQL
+	case "ptr":
+		nullable = esql.NullablePointer
+	default:
+		return nil, fmt.Errorf("unknown nullable type: %s", c.Null)
+	}
+
+	var tagCase esql.TagCase
+	switch c.TagCase {
+	case "", "original":
+		tagCase = esql.TagCaseOriginal
+	case "snake":
+		tagCase = esql.TagCaseSnake
+	case "camel":
+		tagCase = esql.TagCaseCamel
+	default:
+		return nil, fmt.Errorf("unknown tag case: %s", c.TagCase)
+	}
+
+	opts := []esql.GenOption{
+		esql.WithSchema(c.Schema),
+		esql.WithNullable(nullable),
+		esql.WithDecimalType(c.Decimal),
+		esql.WithTemplate(c.Template),
+		esql.WithTags(c.Tags...),
+		esql.WithTagCase(tagCase),
+		esql.WithInitialisms(c.Initialisms...),
+		esql.WithTables(c.Tables...),
+		esql.WithExcludeTables(c.Exclude...),
+		esql.WithCRUD(c.CRUD),
+		esql.WithPackage(c.Package),
+		esql.WithTrimPrefix(c.TrimPrefix...),
+		esql.WithFilePattern(c.Filename),
+		esql.WithForce(c.Force),
+	}
+
+	if c.TypeMap != "" || len(c.Types) > 0 || len(c.Columns) > 0 {
+		m := &esql.TypeMapping{Types: map[string]string{}, Columns: map[string]string{}}
+		if c.TypeMap != "" {
+			loaded, err := esql.LoadTypeMapping(c.TypeMap)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range loaded.Types {
+				m.Types[k] = v
+			}
+			for k, v := range loaded.Columns {
+				m.Columns[k] = v
+			}
+		}
+		for k, v := range c.Types {
+			m.Types[k] = v
+		}
+		for k, v := range c.Columns {
+			m.Columns[k] = v
+		}
+		opts = append(opts, esql.WithTypeMapping(m))
+	}
+
+	return opts, nil
+}
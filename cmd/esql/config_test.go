@@ -0,0 +1,212 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cyj19/esql"
+)
+
+func TestListValuePatterns(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"user, order", []string{"user", "order"}},
+		{`/^t_\d{1,2}$/`, []string{`/^t_\d{1,2}$/`}},
+		{`/^t_\d{1,2}$/,user*, /^(a|b){2,}$/ ,log`, []string{`/^t_\d{1,2}$/`, "user*", `/^(a|b){2,}$/`, "log"}},
+		{"/^a/b,c/,d", []string{"/^a/b,c/", "d"}},
+		{"user,,", []string{"user"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		if err := (listValue{list: &got, patterns: true}).Set(tt.in); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Set(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// 其他列表参数仍按逗号分隔
+	var got []string
+	if err := (listValue{list: &got}).Set("/a,b/"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"/a", "b/"}) {
+		t.Errorf("unexpected list: %q", got)
+	}
+}
+
+// 设置环境变量，测试结束后恢复
+func setEnv(t *testing.T, key string, value *string) {
+	t.Helper()
+
+	old, ok := os.LookupEnv(key)
+	if value == nil {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, *value)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "esql.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestParseConfigPrecedence(t *testing.T) {
+	setEnv(t, passwordEnv, nil)
+	configFile := writeConfig(t, `mode: postgres
+db: file_db
+password: file_password
+port: 6543
+tables: [audit]
+tags: [json]
+null: sql
+crud: true
+`)
+
+	c, err := parseConfig("gen", []string{"-config", configFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != esql.Postgres || c.DB != "file_db" || c.Password != "file_password" || c.Port != 6543 ||
+		!reflect.DeepEqual(c.Tables, []string{"audit"}) || c.Null != "sql" || !c.CRUD {
+		t.Fatalf("the config file is not applied: %+v", c)
+	}
+	// 配置文件中没有的使用默认值
+	if c.Host != "127.0.0.1" || c.Schema != "public" || c.Decimal != "string" {
+		t.Fatalf("unexpected defaults: %+v", c)
+	}
+
+	// 命令行参数优先于配置文件，没有指定的参数保留配置文件中的值
+	c, err = parseConfig("gen", []string{"-config", configFile, "-db", "flag_db", "-null", "ptr", "-crud=false", "-tables", `/^t_\d{1,2}$/,user*`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DB != "flag_db" || c.Null != "ptr" || c.CRUD || !reflect.DeepEqual(c.Tables, []string{`/^t_\d{1,2}$/`, "user*"}) {
+		t.Fatalf("flags should override the config file: %+v", c)
+	}
+	if c.Password != "file_password" || c.Port != 6543 || !reflect.DeepEqual(c.Tags, []string{"json"}) {
+		t.Fatalf("unset flags should keep the config file: %+v", c)
+	}
+
+	// 环境变量中的密码优先于配置文件，命令行参数优先于环境变量
+	password := "env_password"
+	setEnv(t, passwordEnv, &password)
+	if c, err = parseConfig("gen", []string{"-config", configFile}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Password != "env_password" {
+		t.Fatalf("%s should override the config file: %q", passwordEnv, c.Password)
+	}
+	if c, err = parseConfig("gen", []string{"-config", configFile, "-p", "flag_password"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Password != "flag_password" {
+		t.Fatalf("-p should override %s: %q", passwordEnv, c.Password)
+	}
+	if c.dataSource() != "host=127.0.0.1 user=root password=flag_password dbname=file_db port=6543 sslmode=disable" {
+		t.Fatalf("unexpected data source: %s", c.dataSource())
+	}
+}
+
+func TestParseConfigFile(t *testing.T) {
+	setEnv(t, passwordEnv, nil)
+
+	// 指定的配置文件必须存在
+	if _, err := parseConfig("gen", []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Fatal("expected an error for a missing config file")
+	}
+	if _, err := parseConfig("gen", []string{"-config", writeConfig(t, "tables: [")}); err == nil {
+		t.Fatal("expected an error for an invalid config file")
+	}
+
+	// 默认的配置文件可以不存在
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	c, err := parseConfig("gen", []string{"-db", "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != esql.Mysql || c.DB != "test" {
+		t.Fatalf("unexpected config: %+v", c)
+	}
+}
+
+func TestConfigOptions(t *testing.T) {
+	dir := t.TempDir()
+	typeMap := filepath.Join(dir, "typemap.yaml")
+	if err := os.WriteFile(typeMap, []byte("types:\n  text: '[]byte'\n  real: float32\ncolumns:\n  user.email: '[]byte'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ddl := filepath.Join(dir, "schema.sql")
+	if err := os.WriteFile(ddl, []byte("create table user (id integer primary key, email text, nickname text, bio text, score real);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 配置文件中的类型映射优先于typemap文件
+	c, err := parseConfig("gen", []string{"-config", writeConfig(t, `mode: sqlite3
+typemap: `+typeMap+`
+types:
+  text: string
+columns:
+  user.nickname: int64
+`), "-package", "model", "-null", "ptr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := c.options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	savePath := filepath.Join(dir, "model")
+	if err = os.Mkdir(savePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = esql.GenStructByDDL(c.Mode, ddl, savePath, false, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(savePath, "user.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := strings.Join(strings.Fields(string(data)), " ")
+	for _, want := range []string{"package model", "Email []byte", "Nickname *int64", "Bio *string", "Score *float32"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("user.go does not contain %q:\n%s", want, data)
+		}
+	}
+
+	for _, bad := range []config{{Null: "none"}, {TagCase: "kebab"}, {TypeMap: filepath.Join(dir, "missing.yaml")}} {
+		if _, err = bad.options(); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
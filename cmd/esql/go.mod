@@ -5,4 +5,9 @@ go 1.16
 require (
 	github.com/cyj19/esql v0.1.0
 	github.com/go-sql-driver/mysql v1.6.0
+	github.com/lib/pq v1.10.4
+	github.com/mattn/go-sqlite3 v1.14.10
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+replace github.com/cyj19/esql => ../../
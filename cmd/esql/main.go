@@ -1,52 +1,181 @@
 package main
 
 import (
-	"flag"
 	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"strings"
+
 	"github.com/cyj19/esql"
 	_ "github.com/go-sql-driver/mysql"
-	"log"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
-var (
-	mode     = flag.String("mode", esql.Mysql, "the database drive")
-	ip       = flag.String("ip", "127.0.0.1", "the database ip")
-	port     = flag.Int("port", 3306, "the database port")
-	user     = flag.String("u", "root", "the database user")
-	password = flag.String("p", "", "the database password")
-	dsn      = flag.String("dsn", "", "the dataSource")
-	dbName   = flag.String("db", "", "the database name")
-	tag      = flag.Bool("tag", false, "the generated structure needs to be tagged")
-	savePath = flag.String("path", "./", "the path to save file")
-)
+// 版本号，可以通过 -ldflags "-X main.version=v1.0.0" 设置
+var version = ""
+
+const usage = `esql generates Go models from database tables.
+
+Usage:
+  esql <command> [flags]
+
+Commands:
+  gen       generate the model files (default)
+  check     check whether the model files match the tables
+  tables    list the tables to generate
+  version   print the version
+
+Run "esql <command> -h" for the flags of a command.
+Flags are also read from esql.yaml, or the file given by -config.
+`
 
 func main() {
+	log.SetFlags(0)
+
+	// 没有子命令时默认为gen，兼容旧的用法
+	args := os.Args[1:]
+	cmd := "gen"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "gen":
+		err = runGen(args)
+	case "check":
+		err = runCheck(args)
+	case "tables":
+		err = runTables(args)
+	case "version":
+		fmt.Println("esql", getVersion())
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// 生成模型文件
+func runGen(args []string) error {
+	c, err := parseConfig("gen", args)
+	if err != nil {
+		return err
+	}
+	if err = c.validate(); err != nil {
+		return err
+	}
+
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
 
-	flag.Parse()
+	if c.DDL != "" {
+		return esql.GenStructByDDL(c.Mode, c.DDL, c.Path, c.Tag, opts...)
+	}
+	return esql.GenStructByTable(c.Mode, c.dataSource(), c.DB, c.Path, c.Tag, opts...)
+}
+
+// 检查模型文件是否与表结构一致，不一致时以状态码1退出
+func runCheck(args []string) error {
+	c, err := parseConfig("check", args)
+	if err != nil {
+		return err
+	}
+	if err = c.validate(); err != nil {
+		return err
+	}
 
-	// 如果没有提供参数，则打印帮助信息
-	if *mode == "" || *dbName == "" {
-		flag.Usage()
-		return
+	opts, err := c.options()
+	if err != nil {
+		return err
 	}
 
-	var dataSource string
-	if *dsn != "" {
-		dataSource = *dsn
+	var drifts []esql.Drift
+	if c.DDL != "" {
+		drifts, err = esql.CheckStructByDDL(c.Mode, c.DDL, c.Path, c.Tag, opts...)
 	} else {
-		switch *mode {
-		case esql.Mysql:
-			dataSource = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8", *user, *password, *ip, *port, *dbName)
-		case esql.Postgres:
-			dataSource = fmt.Sprintf("user=%s password=%s dbname=%s port=%s sslmode=disable", *user, *password, *dbName, *port)
-		default:
-			//TODO
-		}
+		drifts, err = esql.CheckStructByTable(c.Mode, c.dataSource(), c.DB, c.Path, c.Tag, opts...)
+	}
+	if err != nil {
+		return err
+	}
 
+	for _, drift := range drifts {
+		fmt.Println(drift)
+	}
+	if len(drifts) > 0 {
+		os.Exit(1)
 	}
 
-	err := esql.GenStructByTable(*mode, dataSource, *dbName, *savePath, *tag)
+	return nil
+}
+
+// 列出过滤后需要生成的表
+func runTables(args []string) error {
+	c, err := parseConfig("tables", args)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if err = c.validate(); err != nil {
+		return err
+	}
+
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+
+	var tables []esql.Table
+	if c.DDL != "" {
+		tables, err = esql.TablesByDDL(c.Mode, c.DDL, opts...)
+	} else {
+		tables, err = listTables(c, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if table.Comment != "" {
+			fmt.Printf("%s\t%s\n", table.Name, table.Comment)
+		} else {
+			fmt.Println(table.Name)
+		}
+	}
+
+	return nil
+}
+
+func listTables(c *config, opts []esql.GenOption) ([]esql.Table, error) {
+	db, err := esql.Open(c.Mode, c.dataSource(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer db.DB().Close()
+
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return db.Tables(c.Mode, c.DB, opts...)
+}
+
+// 优先使用编译时设置的版本号，其次是go install时的模块版本
+func getVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
 	}
+	return "(devel)"
 }
@@ -8,6 +8,10 @@ import (
 type DB struct {
 	db     *sql.DB
 	logger Logger
+	// 查询时允许列和字段不完全匹配
+	lenient bool
+	// 生成Insert、Update和Delete语句时使用的方言
+	dialect string
 }
 
 // connection database (连接数据库)
@@ -27,7 +31,7 @@ func Open(dialect string, dataSource string, logger Logger) (*DB, error) {
 		logger = newDefaultLogger()
 	}
 
-	return &DB{db: db, logger: logger}, nil
+	return &DB{db: db, logger: logger, dialect: dialect}, nil
 }
 
 func (e *DB) Ping() error {
@@ -53,3 +57,28 @@ func (e *DB) SetConnMaxIdleTime(d time.Duration) {
 func (e *DB) DB() *sql.DB {
 	return e.db
 }
+
+// SetStrict sets whether QueryRow and QueryRows require every field to match a column, default is true.
+// Columns without a field are always discarded. In lenient mode unmatched fields are left unchanged.
+// (设置查询时是否要求每个字段都有对应的列，默认为true，没有对应字段的列总是被丢弃，非严格模式下不匹配的字段保持原值)
+func (e *DB) SetStrict(strict bool) {
+	e.lenient = !strict
+}
+
+// WithStrict returns a DB sharing the same connections with the strict mode changed, for a single call.
+// (返回共享连接、只修改严格模式的DB，用于单次调用)
+/*
+	err := db.WithStrict(false).QueryRows(&users, "select id, name from user")
+*/
+func (e *DB) WithStrict(strict bool) *DB {
+	db := *e
+	db.lenient = !strict
+	return &db
+}
+
+// SetDialect sets the dialect used by Insert, Update and Delete when the driver name passed to Open is not
+// Mysql, Postgres or SQLite, e.g. esql.Postgres for pgx.
+// (设置Insert、Update和Delete使用的方言，用于Open的驱动名不是Mysql、Postgres或SQLite的情况，如pgx使用esql.Postgres)
+func (e *DB) SetDialect(dialect string) {
+	e.dialect = dialect
+}
@@ -0,0 +1,1044 @@
+package esql
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	tokWord   = iota // 关键字、未引用的标识符和数字
+	tokQuoted        // 引用的标识符，`name`、"name"或SQLite的[name]
+	tokString        // 字符串，'value'或$$value$$
+	tokSymbol        // 其他符号
+)
+
+type ddlToken struct {
+	kind int
+	// 去掉引号后的内容
+	text string
+	// 原始内容
+	raw string
+}
+
+// 从DDL解析出的表
+type ddlTable struct {
+	table   Table
+	columns []column
+	indexes []index
+}
+
+type ddlParser struct {
+	mode   string
+	schema string
+	tables map[string]*ddlTable
+	// 表的定义顺序
+	order []string
+}
+
+// 字段定义中标志类型结束的关键字
+var ddlColumnKeywords = map[string]bool{
+	"not": true, "null": true, "default": true, "primary": true, "unique": true, "references": true,
+	"check": true, "constraint": true, "generated": true, "collate": true, "auto_increment": true,
+	"autoincrement": true, "comment": true, "on": true, "as": true,
+}
+
+// 表约束的起始关键字
+var ddlConstraintKeywords = map[string]bool{
+	"constraint": true, "primary": true, "unique": true, "key": true, "index": true, "foreign": true,
+	"check": true, "fulltext": true, "spatial": true, "exclude": true,
+}
+
+// GenStructByDDL generates model files from CREATE TABLE statements without connecting to a database.
+// ddl is a .sql file or a directory of .sql files, migration files ending with .down.sql are skipped.
+// (通过DDL文件生成模型文件，无需连接数据库，ddl可以是sql文件或目录，会跳过.down.sql文件)
+func GenStructByDDL(mode, ddl, savePath string, hasTag bool, opts ...GenOption) error {
+	var goType func(string, *genOptions) string
+	switch mode {
+	case Mysql:
+		goType = mysqlGoType
+	case Postgres:
+		goType = postgresGoType
+	case SQLite:
+		goType = sqliteGoType
+	default:
+		return fmt.Errorf("unsupported mode: %s", mode)
+	}
+
+	opt := newGenOptions(opts...)
+	if err := opt.loadTemplates(); err != nil {
+		return err
+	}
+
+	src, err := readDDL(ddl)
+	if err != nil {
+		return err
+	}
+
+	p := newDDLParser(mode, opt.schema)
+	p.parse(src)
+
+	tables := make([]Table, 0, len(p.order))
+	for _, name := range p.order {
+		tables = append(tables, p.tables[name].table)
+	}
+
+	return genFiles(tables, savePath, opt, func(table Table, savePath, pack string) error {
+		t := p.tables[table.Name]
+		return genFileByColumns(table, savePath, pack, mode, t.columns, t.indexes, goType, hasTag, opt)
+	})
+}
+
+// TablesByDDL lists the tables that GenStructByDDL would generate, after WithTables and WithExcludeTables are applied.
+// (列出GenStructByDDL会生成的表，已按WithTables和WithExcludeTables过滤)
+func TablesByDDL(mode, ddl string, opts ...GenOption) ([]Table, error) {
+	opt := newGenOptions(opts...)
+	tables, err := allTablesByDDL(mode, ddl, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return opt.filterTables(tables)
+}
+
+// DDL文件中未过滤的所有表
+func allTablesByDDL(mode, ddl string, opt *genOptions) ([]Table, error) {
+	src, err := readDDL(ddl)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newDDLParser(mode, opt.schema)
+	p.parse(src)
+
+	tables := make([]Table, 0, len(p.order))
+	for _, name := range p.order {
+		tables = append(tables, p.tables[name].table)
+	}
+
+	return tables, nil
+}
+
+// 读取DDL文件或目录下的所有sql文件
+func readDDL(ddl string) (string, error) {
+	fi, err := os.Stat(ddl)
+	if err != nil {
+		return "", err
+	}
+
+	if !fi.IsDir() {
+		data, err := os.ReadFile(ddl)
+		return string(data), err
+	}
+
+	files, err := filepath.Glob(filepath.Join(ddl, "*.sql"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	var sb strings.Builder
+	for _, file := range files {
+		if strings.HasSuffix(file, ".down.sql") {
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		sb.Write(data)
+		// 防止文件末尾缺少分号
+		sb.WriteString("\n;\n")
+	}
+
+	return sb.String(), nil
+}
+
+func newDDLParser(mode, schema string) *ddlParser {
+	return &ddlParser{
+		mode:   mode,
+		schema: schema,
+		tables: make(map[string]*ddlTable),
+	}
+}
+
+func (p *ddlParser) parse(src string) {
+	for _, stmt := range splitStatements(lexDDL(src, p.mode)) {
+		p.parseStatement(stmt)
+	}
+}
+
+// MySQL字符串中反斜杠转义的字符，如SHOW CREATE TABLE输出的多行注释中的\n
+func mysqlUnescape(r rune) rune {
+	switch r {
+	case '0':
+		return 0
+	case 'b':
+		return '\b'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'Z':
+		return '\x1a'
+	default:
+		return r
+	}
+}
+
+// 词法分析，跳过注释
+func lexDDL(src, mode string) []ddlToken {
+	var tokens []ddlToken
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(rs) && rs[i+1] == '-', r == '#' && mode == Mysql:
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			end := indexRunes(rs, i+2, []rune("*/"))
+			if end < 0 {
+				return tokens
+			}
+			i = end + 2
+		case r == '\'' || r == '`' || r == '"':
+			kind := tokQuoted
+			if r == '\'' {
+				kind = tokString
+			}
+
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs); j++ {
+				if rs[j] == '\\' && r == '\'' && mode == Mysql && j+1 < len(rs) {
+					j++
+					sb.WriteRune(mysqlUnescape(rs[j]))
+					continue
+				}
+				if rs[j] == r {
+					// 连续两个引号表示转义
+					if j+1 < len(rs) && rs[j+1] == r {
+						sb.WriteRune(r)
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteRune(rs[j])
+			}
+
+			if j >= len(rs) {
+				j = len(rs) - 1
+			}
+			tokens = append(tokens, ddlToken{kind: kind, text: sb.String(), raw: string(rs[i : j+1])})
+			i = j + 1
+		case r == '[' && mode == SQLite:
+			// SQLite兼容SQL Server的[name]引用
+			end := indexRunes(rs, i+1, []rune("]"))
+			if end < 0 {
+				end = len(rs) - 1
+			}
+			tokens = append(tokens, ddlToken{kind: tokQuoted, text: strings.TrimSuffix(string(rs[i+1:end+1]), "]"), raw: string(rs[i : end+1])})
+			i = end + 1
+		case r == '$' && mode == Postgres:
+			// 美元符号引用的字符串，如$$body$$或$tag$body$tag$
+			j := i + 1
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			if j >= len(rs) || rs[j] != '$' {
+				tokens = append(tokens, ddlToken{kind: tokSymbol, text: "$", raw: "$"})
+				i++
+				continue
+			}
+
+			tag := rs[i : j+1]
+			end := indexRunes(rs, j+1, tag)
+			if end < 0 {
+				return tokens
+			}
+
+			tokens = append(tokens, ddlToken{kind: tokString, text: string(rs[j+1 : end]), raw: string(rs[i : end+len(tag)])})
+			i = end + len(tag)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '$' ||
+				(rs[j] == '.' && unicode.IsDigit(r))) {
+				j++
+			}
+
+			word := string(rs[i:j])
+			tokens = append(tokens, ddlToken{kind: tokWord, text: word, raw: word})
+			i = j
+		default:
+			tokens = append(tokens, ddlToken{kind: tokSymbol, text: string(r), raw: string(r)})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// 从start开始查找sub的位置
+func indexRunes(rs []rune, start int, sub []rune) int {
+	for i := start; i+len(sub) <= len(rs); i++ {
+		match := true
+		for j := range sub {
+			if rs[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// 按分号拆分语句
+func splitStatements(tokens []ddlToken) [][]ddlToken {
+	var stmts [][]ddlToken
+	start := 0
+	for i, tok := range tokens {
+		if tok.kind == tokSymbol && tok.text == ";" {
+			if i > start {
+				stmts = append(stmts, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		stmts = append(stmts, tokens[start:])
+	}
+
+	return stmts
+}
+
+// 按最外层的逗号拆分
+func splitTopLevel(tokens []ddlToken) [][]ddlToken {
+	var parts [][]ddlToken
+	depth, start := 0, 0
+	for i, tok := range tokens {
+		if tok.kind != tokSymbol {
+			continue
+		}
+
+		switch tok.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+
+	return parts
+}
+
+// 返回与tokens[start]的左括号匹配的右括号位置
+func groupEnd(tokens []ddlToken, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		if tokens[i].kind != tokSymbol {
+			continue
+		}
+
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return len(tokens) - 1
+}
+
+// 判断第i个token是否是关键字
+func isWord(tokens []ddlToken, i int, words ...string) bool {
+	if i >= len(tokens) || tokens[i].kind != tokWord {
+		return false
+	}
+
+	for _, word := range words {
+		if strings.EqualFold(tokens[i].text, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSymbol(tokens []ddlToken, i int, symbol string) bool {
+	return i < len(tokens) && tokens[i].kind == tokSymbol && tokens[i].text == symbol
+}
+
+// 跳过连续的关键字，如if not exists
+func skipWords(tokens []ddlToken, i int, words ...string) int {
+	for j, word := range words {
+		if !isWord(tokens, i+j, word) {
+			return i
+		}
+	}
+
+	return i + len(words)
+}
+
+// 解析可能带模式的名称，返回模式、名称和下一个位置
+func parseQualifiedName(tokens []ddlToken, i int) (string, string, int) {
+	var parts []string
+	for i < len(tokens) && (tokens[i].kind == tokWord || tokens[i].kind == tokQuoted) {
+		parts = append(parts, tokens[i].text)
+		i++
+		if !isSymbol(tokens, i, ".") {
+			break
+		}
+		i++
+	}
+
+	switch len(parts) {
+	case 0:
+		return "", "", i
+	case 1:
+		return "", parts[0], i
+	default:
+		return parts[len(parts)-2], parts[len(parts)-1], i
+	}
+}
+
+func (p *ddlParser) parseStatement(stmt []ddlToken) {
+	switch {
+	case isWord(stmt, 0, "create"):
+		i := skipWords(stmt, 1, "or", "replace")
+		for isWord(stmt, i, "temporary", "temp", "global", "local", "unlogged") {
+			i++
+		}
+
+		switch {
+		case isWord(stmt, i, "table"):
+			p.parseCreateTable(stmt[i+1:])
+		case isWord(stmt, i, "unique") && isWord(stmt, i+1, "index"):
+			p.parseCreateIndex(stmt[i+2:])
+		}
+	case isWord(stmt, 0, "alter") && isWord(stmt, 1, "table"):
+		p.parseAlterTable(stmt[2:])
+	case isWord(stmt, 0, "comment") && isWord(stmt, 1, "on"):
+		p.parseComment(stmt[2:])
+	case isWord(stmt, 0, "drop") && isWord(stmt, 1, "table"):
+		i := skipWords(stmt, 2, "if", "exists")
+		for _, part := range splitTopLevel(stmt[i:]) {
+			if _, name, _ := parseQualifiedName(part, 0); name != "" {
+				p.dropTable(name)
+			}
+		}
+	}
+}
+
+// 获取表，模式与选项不一致时返回nil
+func (p *ddlParser) table(schema, name string) *ddlTable {
+	if p.mode == Postgres && schema != "" && schema != p.schema {
+		return nil
+	}
+
+	return p.tables[name]
+}
+
+func (p *ddlParser) dropTable(name string) {
+	if _, ok := p.tables[name]; !ok {
+		return
+	}
+
+	delete(p.tables, name)
+	for i, v := range p.order {
+		if v == name {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (p *ddlParser) parseCreateTable(tokens []ddlToken) {
+	i := skipWords(tokens, 0, "if", "not", "exists")
+	schema, name, i := parseQualifiedName(tokens, i)
+	if name == "" || !isSymbol(tokens, i, "(") {
+		// create table ... like/as select 无法解析字段
+		return
+	}
+	if p.mode == Postgres && schema != "" && schema != p.schema {
+		return
+	}
+
+	end := groupEnd(tokens, i)
+	t := &ddlTable{table: Table{Name: name}}
+	for _, def := range splitTopLevel(tokens[i+1 : end]) {
+		p.parseTableElement(t, def)
+	}
+
+	// 表选项中的注释，如comment='用户表'
+	for j := end + 1; j < len(tokens); j++ {
+		if isWord(tokens, j, "comment") {
+			if isSymbol(tokens, j+1, "=") {
+				j++
+			}
+			if j+1 < len(tokens) && tokens[j+1].kind != tokSymbol {
+				t.table.Comment = tokens[j+1].text
+			}
+			break
+		}
+	}
+
+	if p.mode == SQLite {
+		t.setRowIDAlias()
+	}
+
+	p.dropTable(name)
+	p.tables[name] = t
+	p.order = append(p.order, name)
+}
+
+// SQLite单个INTEGER主键是rowid的别名，会自动增长，与从数据库读取表结构时的规则一致
+func (t *ddlTable) setRowIDAlias() {
+	var pks []int
+	for i, c := range t.columns {
+		if c.PrimaryKey {
+			pks = append(pks, i)
+		}
+	}
+
+	if len(pks) == 1 && strings.EqualFold(t.columns[pks[0]].Type, "INTEGER") {
+		t.columns[pks[0]].AutoIncrement = true
+	}
+}
+
+// 解析字段定义或表约束
+func (p *ddlParser) parseTableElement(t *ddlTable, def []ddlToken) {
+	if len(def) == 0 {
+		return
+	}
+
+	if def[0].kind == tokWord && ddlConstraintKeywords[strings.ToLower(def[0].text)] {
+		p.parseConstraint(t, def)
+		return
+	}
+
+	if c, unique, ok := p.parseColumn(def); ok {
+		t.addColumn(c, unique)
+	}
+}
+
+func (t *ddlTable) addColumn(c column, unique bool) {
+	t.columns = append(t.columns, c)
+	if c.PrimaryKey {
+		t.setPrimaryKey([]string{c.Name})
+	}
+	if unique {
+		t.indexes = append(t.indexes, index{Name: c.Name, Columns: []string{c.Name}})
+	}
+}
+
+// 解析表约束，只处理主键和唯一索引
+func (p *ddlParser) parseConstraint(t *ddlTable, def []ddlToken) {
+	var name string
+	i := 0
+	if isWord(def, i, "constraint") {
+		if i+1 < len(def) && !isWord(def, i+1, "primary", "unique") {
+			name = def[i+1].text
+			i++
+		}
+		i++
+	}
+
+	primary := isWord(def, i, "primary")
+	if !primary && !isWord(def, i, "unique") {
+		return
+	}
+
+	i++
+	for i < len(def) && !isSymbol(def, i, "(") {
+		// unique key name (...)
+		if !isWord(def, i, "key", "index", "using", "btree", "hash", "nulls", "not", "distinct") && name == "" {
+			name = def[i].text
+		}
+		i++
+	}
+	if i >= len(def) {
+		return
+	}
+
+	columns, ok := parseIndexColumns(def[i+1 : groupEnd(def, i)])
+	if !ok {
+		return
+	}
+
+	if primary {
+		t.setPrimaryKey(columns)
+		return
+	}
+
+	if name == "" {
+		name = strings.Join(columns, "_")
+	}
+	t.indexes = append(t.indexes, index{Name: name, Columns: columns})
+}
+
+// 解析索引字段，包含表达式时返回false
+func parseIndexColumns(tokens []ddlToken) ([]string, bool) {
+	var columns []string
+	for _, part := range splitTopLevel(tokens) {
+		if len(part) == 0 || part[0].kind == tokSymbol || part[0].kind == tokString {
+			return nil, false
+		}
+		// 函数表达式，如lower(name)；mysql的前缀索引name(10)仍然可以按字段查询
+		if isSymbol(part, 1, "(") && !(len(part) > 2 && part[2].kind == tokWord && unicode.IsDigit(rune(part[2].text[0]))) {
+			return nil, false
+		}
+
+		columns = append(columns, part[0].text)
+	}
+
+	return columns, len(columns) > 0
+}
+
+func (t *ddlTable) setPrimaryKey(columns []string) {
+	t.dropPrimaryKey()
+	t.indexes = append(t.indexes, index{Name: "PRIMARY", Primary: true, Columns: columns})
+
+	primary := make(map[string]bool, len(columns))
+	for _, name := range columns {
+		primary[name] = true
+	}
+	for i := range t.columns {
+		if primary[t.columns[i].Name] {
+			t.columns[i].PrimaryKey = true
+			t.columns[i].Nullable = false
+		}
+	}
+}
+
+func (t *ddlTable) dropPrimaryKey() {
+	t.dropIndex(func(idx index) bool {
+		return idx.Primary
+	})
+	for i := range t.columns {
+		t.columns[i].PrimaryKey = false
+	}
+}
+
+func (t *ddlTable) dropIndex(match func(index) bool) {
+	indexes := t.indexes[:0]
+	for _, idx := range t.indexes {
+		if !match(idx) {
+			indexes = append(indexes, idx)
+		}
+	}
+	t.indexes = indexes
+}
+
+// 解析字段定义，返回字段、是否唯一
+func (p *ddlParser) parseColumn(def []ddlToken) (column, bool, bool) {
+	if len(def) == 0 || def[0].kind != tokWord && def[0].kind != tokQuoted {
+		return column{}, false, false
+	}
+
+	c := column{Name: def[0].text, Nullable: true}
+	typ, i, serial := p.parseType(def, 1)
+	if typ == "" {
+		return column{}, false, false
+	}
+
+	c.Type = typ
+	c.AutoIncrement = serial
+	if serial {
+		c.Nullable = false
+	}
+
+	var unique bool
+	for i < len(def) {
+		switch {
+		case isWord(def, i, "not") && isWord(def, i+1, "null"):
+			c.Nullable = false
+			i += 2
+		case isWord(def, i, "null"):
+			i++
+		case isWord(def, i, "default"):
+			c.Default, i = parseDefault(def, i+1)
+		case isWord(def, i, "auto_increment", "autoincrement"):
+			c.AutoIncrement = true
+			i++
+		case isWord(def, i, "primary") && isWord(def, i+1, "key"):
+			c.PrimaryKey = true
+			c.Nullable = false
+			i += 2
+		case isWord(def, i, "unique"):
+			unique = true
+			i = skipWords(def, i+1, "key")
+		case isWord(def, i, "comment"):
+			if i+1 < len(def) && def[i+1].kind != tokSymbol {
+				c.Comment = def[i+1].text
+			}
+			i += 2
+		case isWord(def, i, "generated"):
+			// generated always/by default as identity 是自增字段，generated always as (expr) 是计算字段
+			for i < len(def) && !isWord(def, i, "as") {
+				i++
+			}
+			i++
+			if isWord(def, i, "identity") {
+				c.AutoIncrement = true
+				c.Nullable = false
+				i++
+			}
+			if isSymbol(def, i, "(") {
+				i = groupEnd(def, i) + 1
+			}
+		case isWord(def, i, "on"):
+			// on delete/update的动作，避免把set null当成可空
+			i += 2
+			if isWord(def, i, "set", "no") {
+				i++
+			}
+			i++
+			if isSymbol(def, i, "(") {
+				i = groupEnd(def, i) + 1
+			}
+		case isWord(def, i, "collate", "constraint"):
+			i += 2
+		case isSymbol(def, i, "("):
+			i = groupEnd(def, i) + 1
+		default:
+			i++
+		}
+	}
+
+	return c, unique, true
+}
+
+// 解析默认值
+func parseDefault(tokens []ddlToken, i int) (string, int) {
+	if i >= len(tokens) {
+		return "", i
+	}
+
+	start := i
+	if isSymbol(tokens, i, "-") {
+		i++
+	}
+	if isSymbol(tokens, i, "(") {
+		i = groupEnd(tokens, i) + 1
+	} else {
+		i++
+		if isSymbol(tokens, i, "(") {
+			i = groupEnd(tokens, i) + 1
+		}
+	}
+	end := i
+
+	// PostgreSQL的类型转换，如'a'::character varying
+	for isSymbol(tokens, i, ":") && isSymbol(tokens, i+1, ":") {
+		i += 2
+		for i < len(tokens) && tokens[i].kind == tokWord && !ddlColumnKeywords[strings.ToLower(tokens[i].text)] {
+			i++
+		}
+	}
+
+	switch {
+	case tokens[start].kind == tokString && end-start == 1:
+		return tokens[start].text, i
+	case isWord(tokens, start, "null") && end-start == 1:
+		return "", i
+	}
+
+	var sb strings.Builder
+	for j := start; j < end && j < len(tokens); j++ {
+		if j > start && tokens[j].kind == tokWord && tokens[j-1].kind == tokWord {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(tokens[j].raw)
+	}
+
+	return sb.String(), i
+}
+
+// 解析字段类型，返回与在线读取时格式一致的类型、下一个位置、是否是serial类型
+func (p *ddlParser) parseType(tokens []ddlToken, i int) (string, int, bool) {
+	var words []string
+	for i < len(tokens) && tokens[i].kind == tokWord && !ddlColumnKeywords[strings.ToLower(tokens[i].text)] {
+		words = append(words, strings.ToLower(tokens[i].text))
+		i++
+		// mysql的类型只有一个单词，之后是unsigned、character set等属性
+		if p.mode == Mysql {
+			break
+		}
+	}
+	if len(words) == 0 {
+		return "", i, false
+	}
+
+	var args string
+	if isSymbol(tokens, i, "(") {
+		end := groupEnd(tokens, i)
+		raws := make([]string, 0, end-i-1)
+		for _, tok := range tokens[i+1 : end] {
+			raws = append(raws, tok.raw)
+		}
+		args = "(" + strings.Join(raws, "") + ")"
+		i = end + 1
+	}
+
+	switch p.mode {
+	case Mysql:
+		typ := words[0] + args
+		for isWord(tokens, i, "unsigned", "signed", "zerofill") {
+			if isWord(tokens, i, "unsigned") {
+				typ += " unsigned"
+			}
+			i++
+		}
+
+		return typ, i, false
+	case Postgres:
+		// timestamp(3) with time zone
+		for isWord(tokens, i, "with", "without", "time", "zone") {
+			words = append(words, strings.ToLower(tokens[i].text))
+			i++
+		}
+
+		var array bool
+		for isSymbol(tokens, i, "[") {
+			array = true
+			i++
+			for i < len(tokens) && !isSymbol(tokens, i, "]") {
+				i++
+			}
+			i++
+		}
+		if isWord(tokens, i, "array") {
+			array = true
+			i++
+		}
+
+		udt, serial := postgresUdtName(strings.Join(words, " "))
+		if array {
+			udt = "_" + udt
+		}
+
+		return udt, i, serial
+	default:
+		return strings.ToUpper(strings.Join(words, " ")) + args, i, false
+	}
+}
+
+// PostgreSQL声明的类型转换为udt_name，与information_schema.columns一致
+func postgresUdtName(typ string) (string, bool) {
+	switch typ {
+	case "smallint", "int2":
+		return "int2", false
+	case "integer", "int", "int4":
+		return "int4", false
+	case "bigint", "int8":
+		return "int8", false
+	case "smallserial", "serial2":
+		return "int2", true
+	case "serial", "serial4":
+		return "int4", true
+	case "bigserial", "serial8":
+		return "int8", true
+	case "real", "float4":
+		return "float4", false
+	case "double precision", "float8", "float":
+		return "float8", false
+	case "decimal", "numeric":
+		return "numeric", false
+	case "boolean", "bool":
+		return "bool", false
+	case "character varying", "varchar":
+		return "varchar", false
+	case "character", "char", "bpchar":
+		return "bpchar", false
+	case "timestamp", "timestamp without time zone":
+		return "timestamp", false
+	case "timestamptz", "timestamp with time zone":
+		return "timestamptz", false
+	case "time", "time without time zone":
+		return "time", false
+	case "timetz", "time with time zone":
+		return "timetz", false
+	default:
+		return typ, false
+	}
+}
+
+// create unique index [concurrently] [if not exists] name on table (columns)
+func (p *ddlParser) parseCreateIndex(tokens []ddlToken) {
+	i := skipWords(tokens, 0, "concurrently")
+	i = skipWords(tokens, i, "if", "not", "exists")
+
+	var name string
+	if !isWord(tokens, i, "on") && i < len(tokens) {
+		name = tokens[i].text
+		i++
+	}
+	for i < len(tokens) && !isWord(tokens, i, "on") {
+		i++
+	}
+
+	i = skipWords(tokens, i+1, "only")
+	schema, table, i := parseQualifiedName(tokens, i)
+	t := p.table(schema, table)
+	if t == nil {
+		return
+	}
+
+	for i < len(tokens) && !isSymbol(tokens, i, "(") {
+		i++
+	}
+	if i >= len(tokens) {
+		return
+	}
+
+	end := groupEnd(tokens, i)
+	// 部分索引不能保证唯一
+	for j := end + 1; j < len(tokens); j++ {
+		if isWord(tokens, j, "where") {
+			return
+		}
+	}
+
+	columns, ok := parseIndexColumns(tokens[i+1 : end])
+	if !ok {
+		return
+	}
+	if name == "" {
+		name = strings.Join(columns, "_")
+	}
+
+	t.indexes = append(t.indexes, index{Name: name, Columns: columns})
+}
+
+// alter table [if exists] [only] table add/drop ...
+func (p *ddlParser) parseAlterTable(tokens []ddlToken) {
+	i := skipWords(tokens, 0, "if", "exists")
+	i = skipWords(tokens, i, "only")
+	schema, name, i := parseQualifiedName(tokens, i)
+	t := p.table(schema, name)
+	if t == nil {
+		return
+	}
+
+	for _, action := range splitTopLevel(tokens[i:]) {
+		switch {
+		case isWord(action, 0, "add"):
+			if !isWord(action, 1, "column") {
+				p.parseTableElement(t, action[1:])
+				continue
+			}
+
+			j := skipWords(action, 2, "if", "not", "exists")
+			if c, unique, ok := p.parseColumn(action[j:]); ok {
+				t.addColumn(c, unique)
+			}
+		case isWord(action, 0, "drop"):
+			switch {
+			case isWord(action, 1, "primary"):
+				t.dropPrimaryKey()
+			case isWord(action, 1, "index", "key", "constraint"):
+				j := skipWords(action, 2, "if", "exists")
+				if j < len(action) {
+					indexName := action[j].text
+					t.dropIndex(func(idx index) bool {
+						return idx.Name == indexName
+					})
+				}
+			default:
+				j := skipWords(action, 1, "column")
+				j = skipWords(action, j, "if", "exists")
+				if j < len(action) {
+					t.dropColumn(action[j].text)
+				}
+			}
+		}
+	}
+}
+
+func (t *ddlTable) dropColumn(name string) {
+	columns := t.columns[:0]
+	for _, c := range t.columns {
+		if c.Name != name {
+			columns = append(columns, c)
+		}
+	}
+	t.columns = columns
+
+	t.dropIndex(func(idx index) bool {
+		for _, c := range idx.Columns {
+			if c == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// comment on table t is '...' / comment on column t.c is '...'
+func (p *ddlParser) parseComment(tokens []ddlToken) {
+	if len(tokens) < 2 || !isWord(tokens, len(tokens)-2, "is") || tokens[len(tokens)-1].kind != tokString {
+		return
+	}
+	comment := tokens[len(tokens)-1].text
+
+	switch {
+	case isWord(tokens, 0, "table"):
+		schema, name, _ := parseQualifiedName(tokens, 1)
+		if t := p.table(schema, name); t != nil {
+			t.table.Comment = comment
+		}
+	case isWord(tokens, 0, "column"):
+		var parts []string
+		for i := 1; i < len(tokens) && (tokens[i].kind == tokWord || tokens[i].kind == tokQuoted); i += 2 {
+			parts = append(parts, tokens[i].text)
+			if !isSymbol(tokens, i+1, ".") {
+				break
+			}
+		}
+		if len(parts) < 2 {
+			return
+		}
+
+		var schema string
+		if len(parts) > 2 {
+			schema = parts[len(parts)-3]
+		}
+		t := p.table(schema, parts[len(parts)-2])
+		if t == nil {
+			return
+		}
+		for i := range t.columns {
+			if t.columns[i].Name == parts[len(parts)-1] {
+				t.columns[i].Comment = comment
+			}
+		}
+	}
+}
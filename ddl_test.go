@@ -0,0 +1,278 @@
+package esql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 按定义顺序返回解析出的表
+func parseDDLTables(mode, src string) []ddlTable {
+	p := newDDLParser(mode, "public")
+	p.parse(src)
+
+	tables := make([]ddlTable, 0, len(p.order))
+	for _, name := range p.order {
+		tables = append(tables, *p.tables[name])
+	}
+
+	return tables
+}
+
+func TestParseDDL(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		src  string
+		want []ddlTable
+	}{
+		{
+			name: "mysql",
+			mode: Mysql,
+			src: "-- users\n" +
+				"/* created by migration */\n" +
+				"CREATE TABLE IF NOT EXISTS `user` (\n" +
+				"  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'user id',\n" +
+				"  `name` varchar(64) NOT NULL DEFAULT '' COMMENT 'it''s the name; with semicolon',\n" +
+				"  `age` tinyint unsigned DEFAULT NULL COMMENT 'age\\nin years, \\'optional\\'\\t\\\\',\n" +
+				"  `balance` decimal(10,2) NOT NULL DEFAULT '0.00',\n" +
+				"  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  UNIQUE KEY `uk_name` (`name`),\n" +
+				"  KEY `idx_age` (`age`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='users';",
+			want: []ddlTable{{
+				table: Table{Name: "user", Comment: "users"},
+				columns: []column{
+					{Name: "id", Type: "bigint(20) unsigned", Comment: "user id", PrimaryKey: true, AutoIncrement: true},
+					{Name: "name", Type: "varchar(64)", Comment: "it's the name; with semicolon"},
+					{Name: "age", Type: "tinyint unsigned", Nullable: true, Comment: "age\nin years, 'optional'\t\\"},
+					{Name: "balance", Type: "decimal(10,2)", Default: "0.00"},
+					{Name: "created_at", Type: "datetime", Default: "CURRENT_TIMESTAMP"},
+				},
+				indexes: []index{
+					{Name: "PRIMARY", Primary: true, Columns: []string{"id"}},
+					{Name: "uk_name", Columns: []string{"name"}},
+				},
+			}},
+		},
+		{
+			name: "mysql composite primary key and alter table",
+			mode: Mysql,
+			src: "create table order_item (order_id int not null, item_id int not null, sku varchar(32) unique, note text, primary key (order_id, item_id));\n" +
+				"alter table order_item add column qty int unsigned not null default 1, drop column note, add unique index uk_sku_qty (sku, qty);",
+			want: []ddlTable{{
+				table: Table{Name: "order_item"},
+				columns: []column{
+					{Name: "order_id", Type: "int", PrimaryKey: true},
+					{Name: "item_id", Type: "int", PrimaryKey: true},
+					{Name: "sku", Type: "varchar(32)", Nullable: true},
+					{Name: "qty", Type: "int unsigned", Default: "1"},
+				},
+				indexes: []index{
+					{Name: "sku", Columns: []string{"sku"}},
+					{Name: "PRIMARY", Primary: true, Columns: []string{"order_id", "item_id"}},
+					{Name: "uk_sku_qty", Columns: []string{"sku", "qty"}},
+				},
+			}},
+		},
+		{
+			name: "postgres",
+			mode: Postgres,
+			src: `create table if not exists public."user" (
+	id bigserial primary key,
+	"Email" character varying(255) not null,
+	nickname text default 'guest'::text,
+	tags text[],
+	created_at timestamp(3) with time zone not null default now(),
+	tenant_id integer references tenant (id) on delete set null,
+	constraint uk_email unique ("Email")
+);
+create table other.audit (id int);
+create unique index uk_nickname on "user" (nickname);
+create unique index uk_lower_email on "user" (lower("Email"));
+create unique index uk_active on "user" (tenant_id) where deleted_at is null;
+comment on table "user" is 'users';
+comment on column public."user"."Email" is 'login email';`,
+			want: []ddlTable{{
+				table: Table{Name: "user", Comment: "users"},
+				columns: []column{
+					{Name: "id", Type: "int8", PrimaryKey: true, AutoIncrement: true},
+					{Name: "Email", Type: "varchar", Comment: "login email"},
+					{Name: "nickname", Type: "text", Nullable: true, Default: "guest"},
+					{Name: "tags", Type: "_text", Nullable: true},
+					{Name: "created_at", Type: "timestamptz", Default: "now()"},
+					{Name: "tenant_id", Type: "int4", Nullable: true},
+				},
+				indexes: []index{
+					{Name: "PRIMARY", Primary: true, Columns: []string{"id"}},
+					{Name: "uk_email", Columns: []string{"Email"}},
+					{Name: "uk_nickname", Columns: []string{"nickname"}},
+				},
+			}},
+		},
+		{
+			name: "sqlite",
+			mode: SQLite,
+			src: `CREATE TABLE [post] (
+	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR(100) NOT NULL, -- the title
+	published BOOLEAN DEFAULT 0,
+	score REAL,
+	UNIQUE (title)
+);
+CREATE TABLE tmp (id INTEGER);
+DROP TABLE IF EXISTS tmp;`,
+			want: []ddlTable{{
+				table: Table{Name: "post"},
+				columns: []column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true, AutoIncrement: true},
+					{Name: "title", Type: "VARCHAR(100)"},
+					{Name: "published", Type: "BOOLEAN", Nullable: true, Default: "0"},
+					{Name: "score", Type: "REAL", Nullable: true},
+				},
+				indexes: []index{
+					{Name: "PRIMARY", Primary: true, Columns: []string{"id"}},
+					{Name: "title", Columns: []string{"title"}},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDDLTables(tt.mode, tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformedDDL(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"not ddl", "select * from user; insert into user values (1);", nil},
+		{"missing name", "create table (id int);", nil},
+		{"missing columns", "create table user;", nil},
+		{"create table as", "create table user2 as select * from user;", nil},
+		{"unclosed parenthesis", "create table user (id int, name varchar(10)", []string{"user"}},
+		{"unclosed string", "create table user (id int default 'abc);", []string{"user"}},
+		{"unclosed comment", "create table user (id int); /* comment", []string{"user"}},
+		{"unclosed quoted name", "create table `user (id int);", nil},
+		{"unclosed bracket", "create table [user (id int);", nil},
+		{"stray tokens", "create table user (, , id int,, primary key ());", []string{"user"}},
+		{"alter unknown table", "alter table missing add column id int; comment on column missing.id is 'x';", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, mode := range []string{Mysql, Postgres, SQLite} {
+				var names []string
+				for _, table := range parseDDLTables(mode, tt.src) {
+					names = append(names, table.table.Name)
+				}
+				if !reflect.DeepEqual(names, tt.want) {
+					t.Errorf("%s: got tables %v, want %v", mode, names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenStructByDDL(t *testing.T) {
+	dir := t.TempDir()
+	ddl := filepath.Join(dir, "migrations")
+	savePath := filepath.Join(dir, "model")
+	for _, path := range []string{ddl, savePath} {
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		"001_user.up.sql":   "create table user (id bigint unsigned not null auto_increment primary key, email varchar(255) not null unique);",
+		"001_user.down.sql": "drop table user;",
+		"002_order.up.sql":  "create table `order` (id int primary key, user_id bigint unsigned not null)",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(ddl, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := GenStructByDDL(Mysql, ddl, savePath, true, WithCRUD(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	user := readFile(t, filepath.Join(savePath, "user.go"))
+	for _, want := range []string{"ID uint64 `esql:\"id,pk,autoincr\"`", "Email string `esql:\"email\"`"} {
+		if !strings.Contains(user, want) {
+			t.Errorf("user.go does not contain %q", want)
+		}
+	}
+	if model := readFile(t, filepath.Join(savePath, "user_model.go")); !strings.Contains(model, "FindOneByEmail(") {
+		t.Error("user_model.go does not contain FindOneByEmail")
+	}
+	if order := readFile(t, filepath.Join(savePath, "order.go")); !strings.Contains(order, "UserID uint64 `esql:\"user_id\"`") {
+		t.Error("order.go does not map bigint unsigned to uint64")
+	}
+}
+
+func TestGenStructByDDLMatchesSQLiteTable(t *testing.T) {
+	schema := `create table account (
+	id integer primary key,
+	email varchar(64) not null unique,
+	nickname text,
+	score real not null default 0,
+	created_at datetime
+);
+create table membership (
+	account_id integer not null,
+	group_id integer not null,
+	role text not null,
+	primary key (account_id, group_id)
+);`
+	db := openSQLite(t, schema)
+
+	dir := t.TempDir()
+	ddl := writeDDL(t, dir, schema)
+	livePath, ddlPath := filepath.Join(dir, "live", "model"), filepath.Join(dir, "ddl", "model")
+	for _, path := range []string{livePath, ddlPath} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := []GenOption{WithCRUD(true), WithNullable(NullablePointer)}
+	if err := db.GenStructByTable(SQLite, "", livePath, true, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenStructByDDL(SQLite, ddl, ddlPath, true, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(livePath, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("expected 4 files, got %v", files)
+	}
+	for _, file := range files {
+		live := readFile(t, file)
+		generated := readFile(t, filepath.Join(ddlPath, filepath.Base(file)))
+		if live != generated {
+			t.Errorf("%s differs:\nlive %s\nddl  %s", filepath.Base(file), live, generated)
+		}
+	}
+	if account := readFile(t, filepath.Join(ddlPath, "account.go")); !strings.Contains(account, "`esql:\"id,pk,autoincr\"`") {
+		t.Errorf("id is not autoincr: %s", account)
+	}
+}
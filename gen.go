@@ -1,13 +1,19 @@
 package esql
 
 import (
+	"database/sql"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
-	"text/template"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -17,12 +23,77 @@ const (
 )
 
 type Table struct {
-	Name string `esql:"table_name"`
+	Name    string `esql:"table_name"`
+	Comment string `esql:"table_comment"`
 }
 
 type TableField struct {
-	Name string `esql:"Field"`
-	Type string `esql:"Type"`
+	Name    string         `esql:"Field"`
+	Type    string         `esql:"Type"`
+	Null    string         `esql:"Null"`
+	Key     string         `esql:"Key"`
+	Default sql.NullString `esql:"Default"`
+	Extra   string         `esql:"Extra"`
+	Comment string         `esql:"Comment"`
+}
+
+type PostgresTableField struct {
+	Name       string `esql:"column_name"`
+	Type       string `esql:"udt_name"`
+	Null       string `esql:"is_nullable"`
+	Default    string `esql:"column_default"`
+	Comment    string `esql:"column_comment"`
+	Identity   string `esql:"is_identity"`
+	PrimaryKey bool   `esql:"is_primary_key"`
+}
+
+type SQLiteTableField struct {
+	Name    string         `esql:"name"`
+	Type    string         `esql:"type"`
+	NotNull int            `esql:"notnull"`
+	Default sql.NullString `esql:"dflt_value"`
+	PK      int            `esql:"pk"`
+}
+
+// 数据库表字段，屏蔽不同数据库的差异
+type column struct {
+	Name          string
+	Type          string
+	Nullable      bool
+	Default       string
+	Comment       string
+	PrimaryKey    bool
+	AutoIncrement bool
+}
+
+type MysqlIndex struct {
+	NonUnique int            `esql:"Non_unique"`
+	Name      string         `esql:"Key_name"`
+	Column    sql.NullString `esql:"Column_name"`
+}
+
+type PostgresIndex struct {
+	Name    string `esql:"index_name"`
+	Primary bool   `esql:"is_primary"`
+	Column  string `esql:"column_name"`
+}
+
+type SQLiteIndex struct {
+	Name    string `esql:"name"`
+	Unique  int    `esql:"unique"`
+	Origin  string `esql:"origin"`
+	Partial int    `esql:"partial"`
+}
+
+type SQLiteIndexColumn struct {
+	Name sql.NullString `esql:"name"`
+}
+
+// 表的唯一索引，屏蔽不同数据库的差异
+type index struct {
+	Name    string
+	Primary bool
+	Columns []string
 }
 
 type Field struct {
@@ -30,20 +101,568 @@ type Field struct {
 	CamelName string
 	Type      string
 	HasTag    bool
+	// 完整的标签内容，如esql:"id" json:"id"
+	Tag string
+	// 原始SQL类型
+	SQLType       string
+	Comment       string
+	Default       string
+	Nullable      bool
+	PrimaryKey    bool
+	AutoIncrement bool
 }
 
 type StructInfo struct {
-	Table   string
+	Table string
+	// 表注释
+	Comment string
 	Package string
 	Name    string
+	Mode    string
 	Fields  []Field
 	Imports map[string]struct{}
+	// 主键字段，按主键中的顺序排列
+	PrimaryKeys []Field
+	// 唯一索引，不包括主键
+	UniqueIndexes []Index
+	// 增删改查代码需要的导入，包括context、database/sql、esql以及主键和唯一索引字段类型的导入
+	KeyImports map[string]struct{}
+}
+
+// Index is a unique index of the table (表的唯一索引)
+type Index struct {
+	Name   string
+	Fields []Field
+}
+
+// 类型所在包的导入路径
+var typeImports = map[string]string{
+	"time": "time",
+	"sql":  "database/sql",
+	"json": "encoding/json",
+	"pq":   "github.com/lib/pq",
+}
+
+// 可空字段对应的sql.Null*类型
+var sqlNullTypes = map[string]string{
+	"string":    "sql.NullString",
+	"bool":      "sql.NullBool",
+	"float32":   "sql.NullFloat64",
+	"float64":   "sql.NullFloat64",
+	"time.Time": "sql.NullTime",
+	"int32":     "sql.NullInt32",
+	"int":       "sql.NullInt64",
+	"int8":      "sql.NullInt64",
+	"int16":     "sql.NullInt64",
+	"int64":     "sql.NullInt64",
+	"uint":      "sql.NullInt64",
+	"uint8":     "sql.NullInt64",
+	"uint16":    "sql.NullInt64",
+	"uint32":    "sql.NullInt64",
+}
+
+// NullableMode decides how nullable columns are generated (可空字段的生成方式)
+type NullableMode int
+
+const (
+	// NullableNone keeps the plain Go type (保持原类型)
+	NullableNone NullableMode = iota
+	// NullableSQL uses sql.NullString, sql.NullInt64, sql.NullTime, etc. (使用sql.Null*类型)
+	NullableSQL
+	// NullablePointer uses pointer types (使用指针类型)
+	NullablePointer
+)
+
+// 生成选项
+type genOptions struct {
+	schema      string
+	nullable    NullableMode
+	decimalType string
+	typeMapping *TypeMapping
+	template    string
+	templates   []genTemplate
+	tags        []string
+	tagCase     TagCase
+	tables      []string
+	excludes    []string
+	crud        bool
+	pack        string
+	// 从表名中去掉的前缀
+	trimPrefixes []string
+	filePattern  string
+	force        bool
+	// 包名对应的导入路径
+	typeImports map[string]string
+	// 生成字段名使用的缩略词
+	initialisms initialismSet
+}
+
+// GenOption customizes model generation (自定义模型生成的选项)
+type GenOption func(*genOptions)
+
+// WithSchema sets the PostgreSQL schema to read tables from, default is public.
+// (设置读取表的PostgreSQL模式，默认为public)
+func WithSchema(schema string) GenOption {
+	return func(o *genOptions) {
+		o.schema = schema
+	}
+}
+
+// WithNullable sets how nullable columns are generated, default is NullableNone.
+// (设置可空字段的生成方式，默认为NullableNone)
+func WithNullable(mode NullableMode) GenOption {
+	return func(o *genOptions) {
+		o.nullable = mode
+	}
+}
+
+// WithDecimalType sets the Go type of decimal columns, default is string.
+// The type can be qualified with its import path, e.g. github.com/shopspring/decimal.Decimal
+// (设置decimal字段的Go类型，默认为string，类型可以带上导入路径)
+func WithDecimalType(typ string) GenOption {
+	return func(o *genOptions) {
+		o.decimalType = o.qualifiedType(typ)
+	}
+}
+
+// TypeMapping holds user defined rules which override the generated Go types.
+// Go types can be qualified with their import path, e.g. github.com/shopspring/decimal.Decimal
+// (自定义类型映射规则，Go类型可以带上导入路径)
+type TypeMapping struct {
+	// SQL type to Go type, e.g. {"datetime": "int64", "int unsigned": "uint64"}
+	// (SQL类型映射，可以是完整类型或不带长度的类型)
+	Types map[string]string `json:"types" yaml:"types"`
+	// Column name, glob pattern or table.column to Go type, e.g. {"*_at": "int64", "order.price": "float64"}
+	// (字段映射，优先级：表名.字段 > 字段 > SQL类型)
+	Columns map[string]string `json:"columns" yaml:"columns"`
+}
+
+// LoadTypeMapping reads type mapping rules from a YAML (.yaml, .yml) or JSON (.json) file.
+// (从YAML或JSON文件读取类型映射规则，按扩展名选择格式)
+/*
+	types:
+	  datetime: int64
+	columns:
+	  "*_at": int64
+	  order.price: github.com/shopspring/decimal.Decimal
+*/
+func LoadTypeMapping(filename string) (*TypeMapping, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var m TypeMapping
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	case ".json":
+		err = json.Unmarshal(data, &m)
+	default:
+		return nil, fmt.Errorf("type mapping %s: unsupported file extension %q, use .yaml, .yml or .json", filename, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse type mapping %s: %w", filename, err)
+	}
+
+	return &m, nil
+}
+
+// WithTypeMapping sets the rules overriding the generated Go types (设置自定义类型映射规则)
+func WithTypeMapping(m *TypeMapping) GenOption {
+	return func(o *genOptions) {
+		if m == nil {
+			o.typeMapping = nil
+			return
+		}
+
+		o.typeMapping = &TypeMapping{
+			Types:   make(map[string]string, len(m.Types)),
+			Columns: make(map[string]string, len(m.Columns)),
+		}
+		for k, v := range m.Types {
+			o.typeMapping.Types[strings.ToLower(k)] = o.qualifiedType(v)
+		}
+		for k, v := range m.Columns {
+			o.typeMapping.Columns[k] = o.qualifiedType(v)
+		}
+	}
+}
+
+// 根据自定义规则获取字段类型
+func (o *genOptions) mappedType(table string, c column) (string, bool) {
+	m := o.typeMapping
+	if m == nil {
+		return "", false
+	}
+
+	// 表名.字段
+	if typ, ok := m.Columns[table+"."+c.Name]; ok {
+		return typ, true
+	}
+	if typ, ok := matchPattern(m.Columns, table+"."+c.Name, true); ok {
+		return typ, true
+	}
+
+	// 字段
+	if typ, ok := m.Columns[c.Name]; ok {
+		return typ, true
+	}
+	if typ, ok := matchPattern(m.Columns, c.Name, false); ok {
+		return typ, true
+	}
+
+	// SQL类型，依次匹配完整类型、不带长度的类型和不带unsigned等修饰的类型
+	sqlType := strings.ToLower(strings.TrimSpace(c.Type))
+	if typ, ok := m.Types[sqlType]; ok {
+		return typ, true
+	}
+	if typ, ok := m.Types[removeLength(sqlType)]; ok {
+		return typ, true
+	}
+	if typ, ok := m.Types[baseType(sqlType)]; ok {
+		return typ, true
+	}
+
+	return "", false
+}
+
+// 去掉类型的长度，如int(10) unsigned为int unsigned
+func removeLength(sqlType string) string {
+	for {
+		start := strings.Index(sqlType, "(")
+		end := strings.Index(sqlType, ")")
+		if start < 0 || end < start {
+			return strings.Join(strings.Fields(sqlType), " ")
+		}
+		sqlType = sqlType[:start] + " " + sqlType[end+1:]
+	}
+}
+
+// 类型的第一个单词，如int(10) unsigned为int
+func baseType(sqlType string) string {
+	if i := strings.IndexAny(sqlType, " ("); i >= 0 {
+		return sqlType[:i]
+	}
+
+	return sqlType
+}
+
+// 按模式匹配字段，多个模式匹配时取排序后的第一个，保证结果稳定
+func matchPattern(rules map[string]string, name string, qualified bool) (string, bool) {
+	patterns := make([]string, 0, len(rules))
+	for pattern := range rules {
+		if strings.Contains(pattern, ".") == qualified {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return rules[pattern], true
+		}
+	}
+
+	return "", false
+}
+
+// TagCase decides the naming of the extra struct tags (额外标签的命名格式)
+type TagCase int
+
+const (
+	// TagCaseOriginal uses the column name as it is (使用原字段名)
+	TagCaseOriginal TagCase = iota
+	// TagCaseSnake uses snake_case (使用下划线格式)
+	TagCaseSnake
+	// TagCaseCamel uses lower camelCase (使用小写驼峰格式)
+	TagCaseCamel
+)
+
+// 支持omitempty的标签，可空字段会加上omitempty
+var omitEmptyTags = map[string]bool{
+	"json": true,
+	"yaml": true,
+	"xml":  true,
+	"toml": true,
+}
+
+// WithTags adds extra struct tags such as json, yaml or form to the generated fields.
+// Nullable columns get omitempty for the json, yaml, xml and toml tags.
+// (为生成的字段添加额外标签，可空字段的json、yaml、xml、toml标签会加上omitempty)
+func WithTags(tags ...string) GenOption {
+	return func(o *genOptions) {
+		o.tags = o.tags[:0]
+		for _, tag := range tags {
+			if tag = strings.TrimSpace(tag); tag != "" && tag != tagName {
+				o.tags = append(o.tags, tag)
+			}
+		}
+	}
+}
+
+// WithTagCase sets the naming of the extra struct tags, default is TagCaseOriginal.
+// (设置额外标签的命名格式，默认为TagCaseOriginal)
+func WithTagCase(c TagCase) GenOption {
+	return func(o *genOptions) {
+		o.tagCase = c
+	}
+}
+
+// WithInitialisms adds initialisms kept in upper case in the generated names besides DefaultInitialisms,
+// e.g. WithInitialisms("SKU") generates SKUID for sku_id. Fields that can't be mapped back get an esql tag,
+// the runtime mapping always uses DefaultInitialisms.
+// (生成名称时除DefaultInitialisms外保持大写的缩略词，字段名不能转回列名时会加上esql标签，运行时的映射始终使用默认缩略词)
+func WithInitialisms(words ...string) GenOption {
+	return func(o *genOptions) {
+		o.initialisms = newInitialismSet(append(append([]string(nil), DefaultInitialisms...), words...))
+	}
+}
+
+// 生成字段的标签
+func (o *genOptions) fieldTag(c column, hasTag bool) string {
+	tags := make([]string, 0, len(o.tags)+1)
+	if hasTag {
+		value := c.Name
+		if c.PrimaryKey {
+			value += ",pk"
+		}
+		if c.AutoIncrement {
+			value += ",autoincr"
+		}
+		tags = append(tags, fmt.Sprintf(`%s:"%s"`, tagName, value))
+	}
+
+	name := c.Name
+	switch o.tagCase {
+	case TagCaseSnake:
+		name = o.initialisms.snake(c.Name)
+	case TagCaseCamel:
+		name = o.initialisms.tagCamel(c.Name)
+	}
+
+	for _, tag := range o.tags {
+		value := name
+		if c.Nullable && omitEmptyTags[tag] {
+			value += ",omitempty"
+		}
+		tags = append(tags, fmt.Sprintf(`%s:"%s"`, tag, value))
+	}
+
+	return strings.Join(tags, " ")
+}
+
+// WithTables only generates the tables matching any of the patterns.
+// A pattern is a table name, a glob such as user_*, or a regular expression wrapped in slashes such as /^t_/
+// (只生成匹配的表，支持表名、通配符和用/包裹的正则表达式)
+func WithTables(patterns ...string) GenOption {
+	return func(o *genOptions) {
+		o.tables = trimPatterns(patterns)
+	}
+}
+
+// WithExcludeTables skips the tables matching any of the patterns, see WithTables for the pattern syntax.
+// (跳过匹配的表，模式的格式与WithTables相同)
+func WithExcludeTables(patterns ...string) GenOption {
+	return func(o *genOptions) {
+		o.excludes = trimPatterns(patterns)
+	}
+}
+
+func trimPatterns(patterns []string) []string {
+	out := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			out = append(out, pattern)
+		}
+	}
+
+	return out
+}
+
+// 按包含和排除规则过滤表
+func (o *genOptions) filterTables(tables []Table) ([]Table, error) {
+	if len(o.tables) == 0 && len(o.excludes) == 0 {
+		return tables, nil
+	}
+
+	out := make([]Table, 0, len(tables))
+	for _, table := range tables {
+		ok, err := o.includeTable(table.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, table)
+		}
+	}
+
+	if len(out) == 0 && len(o.tables) > 0 {
+		return nil, fmt.Errorf("no table matches %s", strings.Join(o.tables, ","))
+	}
+
+	return out, nil
+}
+
+// 表是否在包含规则内且不在排除规则内
+func (o *genOptions) includeTable(name string) (bool, error) {
+	if len(o.tables) > 0 {
+		ok, err := matchTable(o.tables, name)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	ok, err := matchTable(o.excludes, name)
+	return !ok, err
+}
+
+// 表名是否匹配任一模式
+func matchTable(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+			if err != nil {
+				return false, err
+			}
+			if re.MatchString(name) {
+				return true, nil
+			}
+			continue
+		}
+
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("bad table pattern %s: %w", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// WithCRUD also generates a <Table>Model type with Insert, FindOne, FindOneBy<UniqueIndex>, Update and Delete
+// into <table>_model.go (同时生成带增删改查方法的<Table>Model)
+func WithCRUD(crud bool) GenOption {
+	return func(o *genOptions) {
+		o.crud = crud
+	}
+}
+
+// WithTemplate sets a template file, or a directory of *.tpl files, replacing the built-in struct template.
+// A directory generates one file per template and table, named <table>_<template>.go
+// (设置自定义模板文件或模板目录，目录中每个模板为每张表生成<表名>_<模板名>.go)
+func WithTemplate(path string) GenOption {
+	return func(o *genOptions) {
+		o.template = path
+	}
+}
+
+// 解析带导入路径的类型，如github.com/shopspring/decimal.Decimal，返回decimal.Decimal并记录导入路径
+func (o *genOptions) qualifiedType(typ string) string {
+	slash := strings.LastIndex(typ, "/")
+	if slash < 0 {
+		return typ
+	}
+
+	dot := strings.LastIndex(typ, ".")
+	if dot < slash {
+		return typ
+	}
+
+	path, name := typ[:dot], typ[dot+1:]
+	dirs := strings.Split(path, "/")
+	pack := dirs[len(dirs)-1]
+	// 跳过主版本号目录，如github.com/xxx/yyy/v2
+	if len(dirs) > 1 && len(pack) > 1 && pack[0] == 'v' && strings.Trim(pack[1:], "0123456789") == "" {
+		pack = dirs[len(dirs)-2]
+	}
+	// 去掉gopkg.in/yaml.v3的版本后缀
+	pack = strings.Split(pack, ".")[0]
+	pack = strings.ReplaceAll(strings.TrimPrefix(pack, "go-"), "-", "")
+
+	o.typeImports[pack] = path
+	return pack + "." + name
+}
+
+// WithPackage sets the package name of the generated files, default is the last segment of savePath.
+// (设置生成文件的包名，默认为保存路径的最后一级目录名)
+func WithPackage(pack string) GenOption {
+	return func(o *genOptions) {
+		o.pack = pack
+	}
+}
+
+// WithTrimPrefix strips the first matching prefix from table names when naming structs and files,
+// e.g. WithTrimPrefix("t_") generates User in user.go for table t_user.
+// (生成结构体名和文件名时去掉表名的前缀，如t_user生成user.go中的User)
+func WithTrimPrefix(prefixes ...string) GenOption {
+	return func(o *genOptions) {
+		o.trimPrefixes = trimPatterns(prefixes)
+	}
+}
+
+// WithFilePattern sets the file name of the generated files, {table} is replaced by the table name,
+// default is "{table}.go", e.g. "{table}_gen.go".
+// (设置生成文件的文件名，{table}会被替换为表名，默认为"{table}.go")
+func WithFilePattern(pattern string) GenOption {
+	return func(o *genOptions) {
+		o.filePattern = pattern
+	}
+}
+
+// WithForce overwrites existing files even if they don't have the "// Code generated ... DO NOT EDIT." header.
+// (覆盖没有"// Code generated ... DO NOT EDIT."头部注释的已有文件)
+func WithForce(force bool) GenOption {
+	return func(o *genOptions) {
+		o.force = force
+	}
+}
+
+// 去掉表名的前缀
+func (o *genOptions) trimTable(name string) string {
+	for _, prefix := range o.trimPrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return name[len(prefix):]
+		}
+	}
+
+	return name
 }
 
-//go:embed struct.tpl
-var structTemplate string
+// 模型文件的文件名，suffix为模板的后缀
+func (o *genOptions) filename(table, suffix string) string {
+	pattern := o.filePattern
+	if pattern == "" {
+		pattern = "{table}.go"
+	}
+	if !strings.HasSuffix(pattern, ".go") {
+		pattern += ".go"
+	}
+
+	return strings.ReplaceAll(pattern, "{table}", o.trimTable(table)+suffix)
+}
+
+func newGenOptions(opts ...GenOption) *genOptions {
+	o := &genOptions{
+		schema:      "public",
+		decimalType: "string",
+		typeImports: make(map[string]string, len(typeImports)),
+		initialisms: defaultInitialisms,
+	}
+	for pack, path := range typeImports {
+		o.typeImports[pack] = path
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
 
-func GenStructByTable(mode, dsn, dbName, savePath string, hasTag bool) error {
+func GenStructByTable(mode, dsn, dbName, savePath string, hasTag bool, opts ...GenOption) error {
 	db, err := Open(mode, dsn, nil)
 	if err != nil {
 		return err
@@ -54,41 +673,104 @@ func GenStructByTable(mode, dsn, dbName, savePath string, hasTag bool) error {
 		return err
 	}
 
-	return db.GenStructByTable(mode, dbName, savePath, hasTag)
+	return db.GenStructByTable(mode, dbName, savePath, hasTag, opts...)
+}
+
+// Tables lists the tables that GenStructByTable would generate, after WithTables and WithExcludeTables are applied.
+// (列出GenStructByTable会生成的表，已按WithTables和WithExcludeTables过滤)
+func (e *DB) Tables(mode, dbName string, opts ...GenOption) ([]Table, error) {
+	opt := newGenOptions(opts...)
+	tables, err := e.allTables(mode, dbName, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return opt.filterTables(tables)
+}
+
+// 未过滤的所有表
+func (e *DB) allTables(mode, dbName string, opt *genOptions) ([]Table, error) {
+	switch mode {
+	case Mysql:
+		return mysqlTables(e, dbName)
+	case Postgres:
+		return postgresTables(e, opt.schema)
+	case SQLite:
+		return sqliteTables(e)
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
 }
 
-func genStructByMysqlTable(db *DB, dbName, savePath string, hasTag bool) error {
+func mysqlTables(db *DB, dbName string) ([]Table, error) {
 	var tables []Table
-	query := "select table_name from information_schema.tables where table_schema=?"
+	query := "select table_name, table_comment from information_schema.tables where table_schema=?"
 	err := db.QueryRows(&tables, query, dbName)
+	return tables, err
+}
+
+func genStructByMysqlTable(db *DB, dbName, savePath string, hasTag bool, opt *genOptions) error {
+	tables, err := mysqlTables(db, dbName)
 	if err != nil {
 		return err
 	}
 
+	return genFiles(tables, savePath, opt, func(table Table, savePath, pack string) error {
+		return genFileByMysqlTable(db, table, savePath, pack, hasTag, opt)
+	})
+}
+
+// 并发生成每张表的模型文件
+func genFiles(tables []Table, savePath string, opt *genOptions, genFile func(table Table, savePath, pack string) error) error {
+	tables, err := opt.filterTables(tables)
+	if err != nil {
+		return err
+	}
+
+	// 去掉前缀后同名的表会生成相同的文件
+	files := make(map[string]string, len(tables))
+	for _, table := range tables {
+		filename := opt.filename(table.Name, "")
+		if other, ok := files[filename]; ok {
+			return fmt.Errorf("tables %s and %s both generate %s", other, table.Name, filename)
+		}
+		files[filename] = table.Name
+	}
+
 	if len(tables) > 0 {
 		if savePath == "./" || savePath == "." || savePath == "" {
 			savePath, _ = os.Getwd()
 			savePath = strings.ReplaceAll(savePath, "\\", "/")
 		}
 
+		// 生成前检查所有文件，避免只生成了一部分
+		if !opt.force {
+			for _, table := range tables {
+				for _, t := range opt.templates {
+					if err = checkGenerated(savePath + "/" + opt.filename(table.Name, t.suffix)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
 		dirs := strings.Split(savePath, "/")
 		pack := dirs[len(dirs)-1]
+		if opt.pack != "" {
+			pack = opt.pack
+		}
 		errCh := make(chan error, len(tables))
 		wg := &sync.WaitGroup{}
 
 		for _, table := range tables {
 			wg.Add(1)
-			go func(table string) {
+			go func(table Table) {
 				defer wg.Done()
-				errCh <- genFileByMysqlTable(db, table, savePath, pack, hasTag)
-			}(table.Name)
+				errCh <- genFile(table, savePath, pack)
+			}(table)
 		}
 
 		wg.Wait()
-		// 统一格式化
-		cmd := exec.Command("go", "fmt", savePath)
-		cmd.Run()
-
 		close(errCh)
 		for err := range errCh {
 			if err != nil {
@@ -100,89 +782,547 @@ func genStructByMysqlTable(db *DB, dbName, savePath string, hasTag bool) error {
 	return nil
 }
 
+// 根据模板生成模型文件
+func writeStructFile(structInfo StructInfo, savePath string, opt *genOptions) error {
+	for _, t := range opt.templates {
+		filename := savePath + "/" + opt.filename(structInfo.Table, t.suffix)
+		if err := executeTemplate(t.tmpl, structInfo, filename); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Generate model files by Mysql table (通过Mysql表生成模型文件)
-func genFileByMysqlTable(db *DB, table, savePath, pack string, hasTag bool) error {
+func genFileByMysqlTable(db *DB, table Table, savePath, pack string, hasTag bool, opt *genOptions) error {
 	var fs []TableField
-	err := db.QueryRows(&fs, fmt.Sprintf("show columns from %s", table))
+	err := db.QueryRows(&fs, fmt.Sprintf("show full columns from `%s`", table.Name))
+	if err != nil {
+		return err
+	}
+
+	columns := make([]column, 0, len(fs))
+	for _, v := range fs {
+		columns = append(columns, column{
+			Name:          v.Name,
+			Type:          v.Type,
+			Nullable:      v.Null == "YES",
+			Default:       v.Default.String,
+			Comment:       v.Comment,
+			PrimaryKey:    v.Key == "PRI",
+			AutoIncrement: strings.Contains(v.Extra, "auto_increment"),
+		})
+	}
+
+	var is []MysqlIndex
+	err = db.QueryRows(&is, fmt.Sprintf("show index from `%s`", table.Name))
 	if err != nil {
 		return err
 	}
-	if len(fs) > 0 {
-		structInfo := StructInfo{
-			Table:   table,
-			Package: pack,
-			Name:    ConvertToCamel(table),
-			Fields:  make([]Field, 0, len(fs)),
-			Imports: make(map[string]struct{}),
+
+	// 结果已按索引和字段顺序排列
+	indexes := make([]index, 0)
+	positions := make(map[string]int)
+	invalid := make(map[string]bool)
+	for _, v := range is {
+		if v.NonUnique != 0 {
+			continue
+		}
+		// 函数索引没有字段名
+		if !v.Column.Valid {
+			invalid[v.Name] = true
+			continue
 		}
 
-		structInfo.Imports["github.com/cyj19/esql"] = struct{}{}
+		pos, ok := positions[v.Name]
+		if !ok {
+			pos = len(indexes)
+			positions[v.Name] = pos
+			indexes = append(indexes, index{Name: v.Name, Primary: v.Name == "PRIMARY"})
+		}
+		indexes[pos].Columns = append(indexes[pos].Columns, v.Column.String)
+	}
 
-		for _, v := range fs {
-			field := Field{Name: v.Name, CamelName: ConvertToCamel(v.Name), HasTag: hasTag}
-			ot := v.Type
-			v.Type = strings.Split(v.Type, "(")[0]
-			switch v.Type {
-			case "int", "tinyint", "smallint":
-				field.Type = "int"
-				if strings.Contains(ot, "unsigned") {
-					field.Type = "uint"
-				}
-			case "bigint":
-				field.Type = "int64"
-				if strings.Contains(ot, "unsigned") {
-					field.Type = "uint64"
-				}
-			case "char", "varchar", "longtext", "text", "tinytext":
-				field.Type = "string"
-			case "date", "datetime", "timestamp":
-				field.Type = "time.Time"
-				structInfo.Imports["time"] = struct{}{}
-			case "double", "float":
-				field.Type = "float64"
-			default:
-				// 其他类型当成string处理
-				field.Type = "string"
+	return genFileByColumns(table, savePath, pack, Mysql, columns, validIndexes(indexes, invalid), mysqlGoType, hasTag, opt)
+}
+
+// 去掉包含表达式的索引
+func validIndexes(indexes []index, invalid map[string]bool) []index {
+	out := make([]index, 0, len(indexes))
+	for _, idx := range indexes {
+		if !invalid[idx.Name] {
+			out = append(out, idx)
+		}
+	}
+
+	return out
+}
+
+// Mysql类型转换为Go类型
+func mysqlGoType(sqlType string, opt *genOptions) string {
+	// 类型可能是int(10) unsigned、int unsigned或INT UNSIGNED等格式
+	sqlType = strings.ToLower(strings.TrimSpace(sqlType))
+	typ := baseType(sqlType)
+	unsigned := false
+	for _, word := range strings.Fields(sqlType[len(typ):]) {
+		if word == "unsigned" {
+			unsigned = true
+		}
+	}
+
+	switch typ {
+	case "tinyint":
+		// tinyint(1)一般用作布尔值
+		if strings.HasPrefix(sqlType, "tinyint(1)") {
+			return "bool"
+		}
+		if unsigned {
+			return "uint8"
+		}
+		return "int8"
+	case "smallint":
+		if unsigned {
+			return "uint16"
+		}
+		return "int16"
+	case "mediumint", "int", "integer":
+		if unsigned {
+			return "uint32"
+		}
+		return "int32"
+	case "bigint":
+		if unsigned {
+			return "uint64"
+		}
+		return "int64"
+	case "year":
+		return "int16"
+	case "float":
+		return "float32"
+	case "double", "real":
+		return "float64"
+	case "decimal", "numeric":
+		return opt.decimalType
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set":
+		return "string"
+	case "date", "datetime", "timestamp":
+		return "time.Time"
+	case "time":
+		// time的范围超过24小时，不能用time.Time表示
+		return "string"
+	case "json":
+		return "json.RawMessage"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit":
+		return "[]byte"
+	default:
+		// 其他类型当成string处理
+		return "string"
+	}
+}
+
+// 根据表字段生成模型文件
+func genFileByColumns(table Table, savePath, pack, mode string, columns []column, indexes []index, goType func(string, *genOptions) string, hasTag bool, opt *genOptions) error {
+	if len(columns) == 0 {
+		return ErrRecordNotFound
+	}
+
+	structInfo := StructInfo{
+		Table:   table.Name,
+		Comment: table.Comment,
+		Package: pack,
+		Name:    opt.initialisms.camel(opt.trimTable(table.Name)),
+		Mode:    mode,
+		Fields:  make([]Field, 0, len(columns)),
+		Imports: make(map[string]struct{}),
+		// 增删改查代码需要的导入，与字段类型的导入合并去重
+		KeyImports: map[string]struct{}{
+			"context":               {},
+			"database/sql":          {},
+			"github.com/cyj19/esql": {},
+		},
+	}
+
+	structInfo.Imports["github.com/cyj19/esql"] = struct{}{}
+
+	primary := make(map[string]bool)
+	for _, idx := range indexes {
+		if idx.Primary {
+			for _, name := range idx.Columns {
+				primary[name] = true
 			}
+		}
+	}
 
-			structInfo.Fields = append(structInfo.Fields, field)
+	for _, c := range columns {
+		if primary[c.Name] {
+			c.PrimaryKey = true
 		}
 
-		// 解析模板
-		tmpl, err := template.New("structTemplate").Parse(structTemplate)
-		if err != nil {
-			return err
+		// 字段名按运行时的规则转回下划线格式与列名不一致时（如2fa_code生成X2faCode），需要标签才能映射
+		camelName := opt.initialisms.camel(c.Name)
+		needTag := hasTag || ConvertCamelToSnake(camelName) != c.Name
+
+		field := Field{
+			Name:          c.Name,
+			CamelName:     camelName,
+			HasTag:        hasTag,
+			Tag:           opt.fieldTag(c, needTag),
+			SQLType:       c.Type,
+			Comment:       c.Comment,
+			Default:       c.Default,
+			Nullable:      c.Nullable,
+			PrimaryKey:    c.PrimaryKey,
+			AutoIncrement: c.AutoIncrement,
+		}
+		if typ, ok := opt.mappedType(table.Name, c); ok {
+			field.Type = typ
+		} else {
+			field.Type = goType(c.Type, opt)
+		}
+		if c.Nullable {
+			field.Type = nullableType(field.Type, opt.nullable)
 		}
 
-		// 表名作为文件名
-		filename := savePath + "/" + table + ".go"
-		f, err := os.Create(filename)
-		if err != nil {
-			return err
+		addTypeImport(structInfo.Imports, field.Type, opt.typeImports)
+		structInfo.Fields = append(structInfo.Fields, field)
+	}
+
+	structInfo.setIndexes(indexes, opt.typeImports)
+	return writeStructFile(structInfo, savePath, opt)
+}
+
+// 根据索引设置主键和唯一索引字段
+func (s *StructInfo) setIndexes(indexes []index, typeImports map[string]string) {
+	fields := make(map[string]Field, len(s.Fields))
+	for _, field := range s.Fields {
+		fields[field.Name] = field
+	}
+
+	keyFields := func(columns []string) []Field {
+		out := make([]Field, 0, len(columns))
+		for _, name := range columns {
+			field, ok := fields[name]
+			if !ok {
+				return nil
+			}
+			out = append(out, field)
 		}
-		defer f.Close()
 
-		return tmpl.Execute(f, structInfo)
+		return out
 	}
 
-	return ErrRecordNotFound
+	var primary string
+	for _, idx := range indexes {
+		if idx.Primary {
+			s.PrimaryKeys = keyFields(idx.Columns)
+			primary = strings.Join(idx.Columns, ",")
+		}
+	}
+
+	// 没有索引信息时使用字段上的主键标识
+	if len(s.PrimaryKeys) == 0 {
+		for _, field := range s.Fields {
+			if field.PrimaryKey {
+				s.PrimaryKeys = append(s.PrimaryKeys, field)
+			}
+		}
+	}
+
+	seen := map[string]bool{primary: true}
+	for _, idx := range indexes {
+		key := strings.Join(idx.Columns, ",")
+		if idx.Primary || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if fs := keyFields(idx.Columns); len(fs) > 0 {
+			s.UniqueIndexes = append(s.UniqueIndexes, Index{Name: idx.Name, Fields: fs})
+		}
+	}
+
+	for _, field := range s.PrimaryKeys {
+		addTypeImport(s.KeyImports, field.Type, typeImports)
+	}
+	for _, idx := range s.UniqueIndexes {
+		for _, field := range idx.Fields {
+			addTypeImport(s.KeyImports, field.Type, typeImports)
+		}
+	}
+}
+
+// 可空字段的类型转换
+func nullableType(goType string, mode NullableMode) string {
+	if mode == NullableNone {
+		return goType
+	}
+
+	if mode == NullableSQL {
+		if t, ok := sqlNullTypes[goType]; ok {
+			return t
+		}
+	}
+
+	// 切片本身可以为nil，无需转换
+	if strings.HasPrefix(goType, "[]") || goType == "json.RawMessage" || strings.HasPrefix(goType, "pq.") {
+		return goType
+	}
+
+	// 没有对应的sql.Null*类型则使用指针
+	return "*" + goType
 }
 
-func genStructByPostgresSqlTable(db *DB, dbName, savePath string, hasTag bool) error {
+// 根据类型的包名添加导入路径
+func addTypeImport(imports map[string]struct{}, goType string, typeImports map[string]string) {
+	goType = strings.TrimLeft(goType, "*[]")
+	if i := strings.Index(goType, "."); i > 0 {
+		if path, ok := typeImports[goType[:i]]; ok {
+			imports[path] = struct{}{}
+		}
+	}
+}
+
+func postgresTables(db *DB, schema string) ([]Table, error) {
 	var tables []Table
-	query := "select tablename as table_name from pg_tables where schemaname='public'"
-	err := db.QueryRows(&tables, query)
+	query := `select tablename as table_name,
+	coalesce(obj_description(format('%I.%I', schemaname, tablename)::regclass, 'pg_class'), '') as table_comment
+	from pg_tables where schemaname=$1`
+	err := db.QueryRows(&tables, query, schema)
+	return tables, err
+}
+
+func genStructByPostgresSqlTable(db *DB, dbName, savePath string, hasTag bool, opt *genOptions) error {
+	tables, err := postgresTables(db, opt.schema)
 	if err != nil {
 		return err
 	}
 
-	//TODO
+	return genFiles(tables, savePath, opt, func(table Table, savePath, pack string) error {
+		return genFileByPostgresTable(db, opt.schema, table, savePath, pack, hasTag, opt)
+	})
+}
 
-	return nil
+// Generate model files by PostgreSQL table (通过PostgreSQL表生成模型文件)
+func genFileByPostgresTable(db *DB, schema string, table Table, savePath, pack string, hasTag bool, opt *genOptions) error {
+	var fs []PostgresTableField
+	query := `select c.column_name, c.udt_name, c.is_nullable, coalesce(c.column_default, '') as column_default,
+	coalesce(col_description(format('%I.%I', c.table_schema, c.table_name)::regclass, c.ordinal_position), '') as column_comment,
+	c.is_identity,
+	exists(select 1 from information_schema.table_constraints tc
+		join information_schema.key_column_usage kcu
+		on tc.constraint_schema=kcu.constraint_schema and tc.constraint_name=kcu.constraint_name
+		where tc.constraint_type='PRIMARY KEY' and tc.table_schema=c.table_schema and tc.table_name=c.table_name
+		and kcu.column_name=c.column_name) as is_primary_key
+	from information_schema.columns c where c.table_schema=$1 and c.table_name=$2 order by c.ordinal_position`
+	err := db.QueryRows(&fs, query, schema, table.Name)
+	if err != nil {
+		return err
+	}
+
+	columns := make([]column, 0, len(fs))
+	for _, v := range fs {
+		columns = append(columns, column{
+			Name:       v.Name,
+			Type:       v.Type,
+			Nullable:   v.Null == "YES",
+			Default:    v.Default,
+			Comment:    v.Comment,
+			PrimaryKey: v.PrimaryKey,
+			// serial使用序列，identity使用标识列
+			AutoIncrement: strings.HasPrefix(v.Default, "nextval(") || v.Identity == "YES",
+		})
+	}
+
+	var is []PostgresIndex
+	// 只查询不带条件、不含表达式的唯一索引
+	query = `select i.relname as index_name, ix.indisprimary as is_primary, a.attname as column_name
+	from pg_index ix
+	join pg_class t on t.oid=ix.indrelid
+	join pg_class i on i.oid=ix.indexrelid
+	join pg_namespace n on n.oid=t.relnamespace
+	join lateral unnest(ix.indkey) with ordinality as k(attnum, seq) on true
+	join pg_attribute a on a.attrelid=t.oid and a.attnum=k.attnum
+	where ix.indisunique and ix.indpred is null and ix.indexprs is null and n.nspname=$1 and t.relname=$2
+	order by i.relname, k.seq`
+	err = db.QueryRows(&is, query, schema, table.Name)
+	if err != nil {
+		return err
+	}
+
+	indexes := make([]index, 0)
+	positions := make(map[string]int)
+	for _, v := range is {
+		pos, ok := positions[v.Name]
+		if !ok {
+			pos = len(indexes)
+			positions[v.Name] = pos
+			indexes = append(indexes, index{Name: v.Name, Primary: v.Primary})
+		}
+		indexes[pos].Columns = append(indexes[pos].Columns, v.Column)
+	}
+
+	return genFileByColumns(table, savePath, pack, Postgres, columns, indexes, postgresGoType, hasTag, opt)
 }
 
-func genStructBySQLiteTable(db *DB, dbName, savePath string, hasTag bool) error {
-	//TODO
+// PostgreSQL类型转换为Go类型
+func postgresGoType(udtName string, opt *genOptions) string {
+	// 数组类型的udt_name以下划线开头
+	if strings.HasPrefix(udtName, "_") {
+		switch udtName {
+		case "_int2", "_int4", "_int8":
+			return "pq.Int64Array"
+		case "_float4", "_float8":
+			return "pq.Float64Array"
+		case "_bool":
+			return "pq.BoolArray"
+		case "_bytea":
+			return "pq.ByteaArray"
+		default:
+			return "pq.StringArray"
+		}
+	}
 
-	return nil
+	switch udtName {
+	case "int2":
+		return "int16"
+	case "int4":
+		return "int32"
+	case "int8":
+		return "int64"
+	case "float4":
+		return "float32"
+	case "float8":
+		return "float64"
+	case "bool":
+		return "bool"
+	case "date", "time", "timetz", "timestamp", "timestamptz":
+		return "time.Time"
+	case "numeric":
+		return opt.decimalType
+	case "json", "jsonb":
+		return "json.RawMessage"
+	case "bytea":
+		return "[]byte"
+	default:
+		// uuid、text、varchar等当成string处理，避免丢失精度
+		return "string"
+	}
+}
+
+func sqliteTables(db *DB) ([]Table, error) {
+	var tables []Table
+	query := "select name as table_name, '' as table_comment from sqlite_master where type='table' and name not like 'sqlite_%'"
+	err := db.QueryRows(&tables, query)
+	return tables, err
+}
+
+func genStructBySQLiteTable(db *DB, dbName, savePath string, hasTag bool, opt *genOptions) error {
+	tables, err := sqliteTables(db)
+	if err != nil {
+		return err
+	}
+
+	return genFiles(tables, savePath, opt, func(table Table, savePath, pack string) error {
+		return genFileBySQLiteTable(db, table, savePath, pack, hasTag, opt)
+	})
+}
+
+// Generate model files by SQLite table (通过SQLite表生成模型文件)
+func genFileBySQLiteTable(db *DB, table Table, savePath, pack string, hasTag bool, opt *genOptions) error {
+	var fs []SQLiteTableField
+	err := db.QueryRows(&fs, fmt.Sprintf("pragma table_info(`%s`)", table.Name))
+	if err != nil {
+		return err
+	}
+
+	var pks int
+	for _, v := range fs {
+		if v.PK > 0 {
+			pks++
+		}
+	}
+
+	columns := make([]column, 0, len(fs))
+	for _, v := range fs {
+		columns = append(columns, column{
+			Name: v.Name,
+			Type: v.Type,
+			// 主键不可为空
+			Nullable:   v.NotNull == 0 && v.PK == 0,
+			Default:    v.Default.String,
+			PrimaryKey: v.PK > 0,
+			// 单个INTEGER主键是rowid的别名，会自动增长
+			AutoIncrement: v.PK > 0 && pks == 1 && strings.EqualFold(v.Type, "INTEGER"),
+		})
+	}
+
+	indexes := make([]index, 0)
+	// 主键按pk的值排序，INTEGER主键不会出现在索引列表中
+	if pks > 0 {
+		primary := index{Name: "PRIMARY", Primary: true, Columns: make([]string, pks)}
+		for _, v := range fs {
+			if v.PK > 0 && v.PK <= pks {
+				primary.Columns[v.PK-1] = v.Name
+			}
+		}
+		indexes = append(indexes, primary)
+	}
+
+	var is []SQLiteIndex
+	err = db.QueryRows(&is, fmt.Sprintf("pragma index_list(`%s`)", table.Name))
+	if err != nil {
+		return err
+	}
+
+	invalid := make(map[string]bool)
+	for _, v := range is {
+		if v.Unique == 0 || v.Partial != 0 || v.Origin == "pk" {
+			continue
+		}
+
+		var ics []SQLiteIndexColumn
+		err = db.QueryRows(&ics, fmt.Sprintf("pragma index_info(`%s`)", v.Name))
+		if err != nil {
+			return err
+		}
+
+		idx := index{Name: v.Name}
+		for _, ic := range ics {
+			// 表达式索引没有字段名
+			if !ic.Name.Valid {
+				invalid[v.Name] = true
+				break
+			}
+			idx.Columns = append(idx.Columns, ic.Name.String)
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return genFileByColumns(table, savePath, pack, SQLite, columns, validIndexes(indexes, invalid), sqliteGoType, hasTag, opt)
+}
+
+// SQLite类型转换为Go类型
+func sqliteGoType(declType string, opt *genOptions) string {
+	typ := strings.ToUpper(strings.Split(declType, "(")[0])
+	// 先匹配驱动能识别的声明类型，再按照SQLite的类型亲和性规则处理
+	switch {
+	case typ == "BOOLEAN" || typ == "BOOL":
+		return "bool"
+	case typ == "DATE" || typ == "DATETIME" || typ == "TIMESTAMP":
+		return "time.Time"
+	case typ == "DECIMAL" || typ == "NUMERIC":
+		return opt.decimalType
+	case strings.Contains(typ, "INT"):
+		return "int64"
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"), strings.Contains(typ, "TEXT"):
+		return "string"
+	case typ == "" || strings.Contains(typ, "BLOB"):
+		return "[]byte"
+	case strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOA"), strings.Contains(typ, "DOUB"):
+		return "float64"
+	default:
+		// NUMERIC亲和性
+		return "float64"
+	}
 }
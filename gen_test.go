@@ -0,0 +1,663 @@
+package esql
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenStructBySQLiteTable(t *testing.T) {
+	db := openSQLite(t, `create table user_account (
+	id integer primary key autoincrement,
+	email text not null unique,
+	nickname text,
+	balance real not null default 0
+);
+create table order_item (
+	order_id integer not null,
+	item_id integer not null,
+	quantity integer not null,
+	primary key (order_id, item_id)
+);`)
+
+	savePath := filepath.Join(t.TempDir(), "model")
+	if err := os.Mkdir(savePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GenStructByTable(SQLite, "", savePath, true, WithCRUD(true), WithNullable(NullableSQL)); err != nil {
+		t.Fatal(err)
+	}
+
+	user := readFile(t, filepath.Join(savePath, "user_account.go"))
+	for _, want := range []string{
+		"// Code generated by esql. DO NOT EDIT.",
+		"package model",
+		"type UserAccount struct",
+		"ID int64 `esql:\"id,pk,autoincr\"`",
+		"Email string `esql:\"email\"`",
+		"Nickname sql.NullString `esql:\"nickname\"`",
+		"Balance float64 `esql:\"balance\"`",
+		"UserAccountFieldsWithPlaceHolder = esql.RawUpdateFieldsWithPlaceHolder(esql.RawUpdateFieldNames(&UserAccount{}), \"`id`\")",
+		`return []string{"id"}`,
+	} {
+		if !strings.Contains(user, want) {
+			t.Errorf("user_account.go does not contain %q", want)
+		}
+	}
+
+	model := readFile(t, filepath.Join(savePath, "user_account_model.go"))
+	for _, want := range []string{"func (m *UserAccountModel) FindOne(", "func (m *UserAccountModel) FindOneByEmail("} {
+		if !strings.Contains(model, want) {
+			t.Errorf("user_account_model.go does not contain %q", want)
+		}
+	}
+
+	item := readFile(t, filepath.Join(savePath, "order_item.go"))
+	if !strings.Contains(item, `return []string{"order_id", "item_id"}`) {
+		t.Errorf("order_item.go has the wrong primary key")
+	}
+}
+
+func TestGenStructFilterTables(t *testing.T) {
+	db := openSQLite(t, `create table t_1 (id integer primary key);
+create table t_12 (id integer primary key);
+create table t_123 (id integer primary key);
+create table user (id integer primary key);
+create table user_log (id integer primary key);
+create table audit (id integer primary key);`)
+
+	tests := []struct {
+		name     string
+		tables   []string
+		excludes []string
+		want     []string
+	}{
+		{"all", nil, nil, []string{"audit.go", "t_1.go", "t_12.go", "t_123.go", "user.go", "user_log.go"}},
+		{"glob", []string{"user*"}, nil, []string{"user.go", "user_log.go"}},
+		{"regexp", []string{`/^t_\d{1,2}$/`}, nil, []string{"t_1.go", "t_12.go"}},
+		{"glob_and_regexp", []string{`/^t_\d{1,2}$/`, "user*"}, []string{"user_log"}, []string{"t_1.go", "t_12.go", "user.go"}},
+		{"exclude_regexp", nil, []string{"/^t_/", "?????"}, []string{"user.go", "user_log.go"}},
+		{"exclude_wins", []string{"audit", "user"}, []string{"audit"}, []string{"user.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			savePath := t.TempDir()
+			opts := []GenOption{WithPackage("model"), WithTables(tt.tables...), WithExcludeTables(tt.excludes...)}
+			if err := db.GenStructByTable(SQLite, "", savePath, false, opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := filepath.Glob(filepath.Join(savePath, "*.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range files {
+				files[i] = filepath.Base(files[i])
+			}
+			if strings.Join(files, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("generated %v, want %v", files, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.Tables(SQLite, "", WithTables("/t_(/")); err == nil {
+		t.Fatal("expected an error for an invalid regexp")
+	}
+}
+
+func readFile(t *testing.T, filename string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 忽略gofmt的对齐
+	return strings.Join(strings.Fields(string(data)), " ")
+}
+
+func TestMysqlGoType(t *testing.T) {
+	opt := newGenOptions()
+	tests := []struct {
+		sqlType, want string
+	}{
+		{"tinyint(1)", "bool"},
+		{"tinyint(4)", "int8"},
+		{"tinyint unsigned", "uint8"},
+		{"smallint(5) unsigned", "uint16"},
+		{"smallint unsigned", "uint16"},
+		{"mediumint", "int32"},
+		{"int", "int32"},
+		{"int unsigned", "uint32"},
+		{"int(10) unsigned zerofill", "uint32"},
+		{"INT UNSIGNED", "uint32"},
+		{"bigint unsigned", "uint64"},
+		{"bigint(20)", "int64"},
+		{"float", "float32"},
+		{"double unsigned", "float64"},
+		{"decimal(10,2) unsigned", "string"},
+		{"varchar(255)", "string"},
+		{"enum('a','b unsigned')", "string"},
+		{"datetime(3)", "time.Time"},
+		{"time", "string"},
+		{"json", "json.RawMessage"},
+		{"varbinary(16)", "[]byte"},
+		{"bit(1)", "[]byte"},
+	}
+	for _, tt := range tests {
+		if got := mysqlGoType(tt.sqlType, opt); got != tt.want {
+			t.Errorf("mysqlGoType(%q) = %s, want %s", tt.sqlType, got, tt.want)
+		}
+	}
+}
+
+func TestPostgresGoType(t *testing.T) {
+	opt := newGenOptions(WithDecimalType("github.com/shopspring/decimal.Decimal"))
+	tests := []struct {
+		udtName, want string
+	}{
+		{"int2", "int16"},
+		{"int4", "int32"},
+		{"int8", "int64"},
+		{"float4", "float32"},
+		{"float8", "float64"},
+		{"numeric", "decimal.Decimal"},
+		{"bool", "bool"},
+		{"text", "string"},
+		{"varchar", "string"},
+		{"bpchar", "string"},
+		{"uuid", "string"},
+		{"date", "time.Time"},
+		{"timestamp", "time.Time"},
+		{"timestamptz", "time.Time"},
+		{"json", "json.RawMessage"},
+		{"jsonb", "json.RawMessage"},
+		{"bytea", "[]byte"},
+		{"_int4", "pq.Int64Array"},
+		{"_int8", "pq.Int64Array"},
+		{"_float8", "pq.Float64Array"},
+		{"_bool", "pq.BoolArray"},
+		{"_bytea", "pq.ByteaArray"},
+		{"_text", "pq.StringArray"},
+		{"_uuid", "pq.StringArray"},
+	}
+	for _, tt := range tests {
+		if got := postgresGoType(tt.udtName, opt); got != tt.want {
+			t.Errorf("postgresGoType(%q) = %s, want %s", tt.udtName, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteGoType(t *testing.T) {
+	opt := newGenOptions(WithDecimalType("github.com/shopspring/decimal.Decimal"))
+	tests := []struct {
+		declType, want string
+	}{
+		{"integer", "int64"},
+		{"BIGINT", "int64"},
+		{"boolean", "bool"},
+		{"datetime", "time.Time"},
+		{"decimal(10,2)", "decimal.Decimal"},
+		{"NUMERIC", "decimal.Decimal"},
+		{"varchar(255)", "string"},
+		{"text", "string"},
+		{"blob", "[]byte"},
+		{"", "[]byte"},
+		{"real", "float64"},
+		{"double precision", "float64"},
+		{"money", "float64"},
+	}
+	for _, tt := range tests {
+		if got := sqliteGoType(tt.declType, opt); got != tt.want {
+			t.Errorf("sqliteGoType(%q) = %s, want %s", tt.declType, got, tt.want)
+		}
+	}
+
+	if got := sqliteGoType("decimal(10,2)", newGenOptions()); got != "string" {
+		t.Errorf("sqliteGoType(decimal) = %s, want string by default", got)
+	}
+}
+
+func TestGenStructByTableUnknownMode(t *testing.T) {
+	db := openSQLite(t, "create table user (id integer primary key);")
+	err := db.GenStructByTable("sqlite", "", t.TempDir(), false)
+	if err == nil || !strings.Contains(err.Error(), `unsupported mode "sqlite"`) {
+		t.Fatalf("expected an unsupported mode error, got %v", err)
+	}
+}
+
+func TestLoadTypeMapping(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"typemap.yaml": "types:\n  datetime: int64\ncolumns:\n  \"*_at\": int64\n  order.price: github.com/shopspring/decimal.Decimal\n",
+		"typemap.yml":  "types: {datetime: int64}\ncolumns: {\"*_at\": int64, order.price: github.com/shopspring/decimal.Decimal}\n",
+		"typemap.json": `{"types": {"datetime": "int64"}, "columns": {"*_at": "int64", "order.price": "github.com/shopspring/decimal.Decimal"}}`,
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := LoadTypeMapping(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if m.Types["datetime"] != "int64" || m.Columns["*_at"] != "int64" || m.Columns["order.price"] != "github.com/shopspring/decimal.Decimal" {
+			t.Errorf("%s: unexpected mapping %+v", name, m)
+		}
+	}
+
+	filename := filepath.Join(dir, "typemap.txt")
+	if err := os.WriteFile(filename, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTypeMapping(filename); err == nil || !strings.Contains(err.Error(), "unsupported file extension") {
+		t.Errorf("expected an unsupported extension error, got %v", err)
+	}
+}
+
+func TestMappedType(t *testing.T) {
+	opt := newGenOptions(WithTypeMapping(&TypeMapping{
+		Types:   map[string]string{"int unsigned": "uint64", "datetime": "int64"},
+		Columns: map[string]string{"*_at": "string", "order.price": "github.com/shopspring/decimal.Decimal"},
+	}))
+	tests := []struct {
+		table, column, sqlType, want string
+	}{
+		{"user", "id", "int(10) unsigned", "uint64"},
+		{"user", "id", "int unsigned", "uint64"},
+		{"user", "birthday", "datetime(3)", "int64"},
+		{"user", "created_at", "datetime", "string"},
+		{"order", "price", "decimal(10,2)", "decimal.Decimal"},
+		{"user", "price", "decimal(10,2)", ""},
+	}
+	for _, tt := range tests {
+		got, _ := opt.mappedType(tt.table, column{Name: tt.column, Type: tt.sqlType})
+		if got != tt.want {
+			t.Errorf("mappedType(%s.%s %s) = %q, want %q", tt.table, tt.column, tt.sqlType, got, tt.want)
+		}
+	}
+}
+
+func TestGenStructWithInitialisms(t *testing.T) {
+	db := openSQLite(t, `create table product (id integer primary key, sku_id text not null, vip_level integer not null);`)
+
+	savePath := filepath.Join(t.TempDir(), "model")
+	if err := os.Mkdir(savePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GenStructByTable(SQLite, "", savePath, false, WithInitialisms("SKU", "VIP")); err != nil {
+		t.Fatal(err)
+	}
+
+	product := readFile(t, filepath.Join(savePath, "product.go"))
+	// 运行时SKUID映射为skuid，需要标签；VIPLevel能映射回vip_level，不需要标签
+	for _, want := range []string{"SKUID string `esql:\"sku_id\"`", "VIPLevel int64 }"} {
+		if !strings.Contains(product, want) {
+			t.Errorf("product.go does not contain %q", want)
+		}
+	}
+}
+
+// 在模块内生成代码并用go vet做类型检查，生成的代码需要导入esql
+func vetGenerated(t *testing.T, gen func(savePath string) error) {
+	t.Helper()
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	savePath, err := os.MkdirTemp(".", "genvet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(savePath)
+	})
+
+	if err = gen(savePath); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(goBin, "vet", "./"+filepath.Base(savePath))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, out)
+	}
+}
+
+func TestGenCRUDCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go vet")
+	}
+
+	db := openSQLite(t, `create table account (
+	id integer primary key autoincrement,
+	email varchar(64) unique null,
+	created_at datetime,
+	score decimal(10,2)
+);`)
+	for _, nullable := range []NullableMode{NullableSQL, NullablePointer} {
+		vetGenerated(t, func(savePath string) error {
+			return db.GenStructByTable(SQLite, "", savePath, true, WithCRUD(true), WithNullable(nullable))
+		})
+	}
+
+	ddls := map[string]string{
+		Mysql:    "create table account (id bigint unsigned not null auto_increment primary key, email varchar(64) null unique, created_at datetime null);",
+		Postgres: "create table account (id bigserial primary key, email varchar(64) unique null, created_at timestamptz);",
+		SQLite:   "create table account (id integer primary key, email varchar(64) unique null, created_at datetime);",
+	}
+	for mode, ddl := range ddls {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "schema.sql"), []byte(ddl), 0644); err != nil {
+			t.Fatal(err)
+		}
+		vetGenerated(t, func(savePath string) error {
+			return GenStructByDDL(mode, dir, savePath, true, WithCRUD(true), WithNullable(NullableSQL))
+		})
+	}
+}
+
+// 生成到临时目录下的model目录
+func genModel(t *testing.T, gen func(savePath string) error) string {
+	t.Helper()
+
+	savePath := filepath.Join(t.TempDir(), "model")
+	if err := os.Mkdir(savePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen(savePath); err != nil {
+		t.Fatal(err)
+	}
+
+	return savePath
+}
+
+const nullableDDL = `create table profile (
+	id integer primary key autoincrement,
+	name text not null,
+	nickname text,
+	age integer,
+	score real,
+	active boolean,
+	birthday datetime,
+	avatar blob
+);`
+
+func TestGenStructWithNullable(t *testing.T) {
+	db := openSQLite(t, nullableDDL)
+
+	tests := []struct {
+		nullable NullableMode
+		want     []string
+	}{
+		{NullableNone, []string{"Nickname string", "Age int64", "Score float64", "Active bool", "Birthday time.Time", "Avatar []byte"}},
+		{NullableSQL, []string{"Nickname sql.NullString", "Age sql.NullInt64", "Score sql.NullFloat64", "Active sql.NullBool",
+			"Birthday sql.NullTime", "Avatar []byte", `"database/sql"`}},
+		{NullablePointer, []string{"Nickname *string", "Age *int64", "Score *float64", "Active *bool", "Birthday *time.Time",
+			"Avatar []byte", `"time"`}},
+	}
+	for _, tt := range tests {
+		savePath := genModel(t, func(savePath string) error {
+			return db.GenStructByTable(SQLite, "", savePath, false, WithNullable(tt.nullable))
+		})
+
+		src := readFile(t, filepath.Join(savePath, "profile.go"))
+		// 主键和非空列不受影响
+		for _, want := range append(tt.want, "ID int64", "Name string") {
+			if !strings.Contains(src, want) {
+				t.Errorf("nullable %d: profile.go does not contain %q:\n%s", tt.nullable, want, src)
+			}
+		}
+		if tt.nullable != NullableSQL && strings.Contains(src, `"database/sql"`) {
+			t.Errorf("nullable %d: profile.go should not import database/sql", tt.nullable)
+		}
+		if tt.nullable == NullableSQL && strings.Contains(src, `"time"`) {
+			t.Errorf("nullable %d: profile.go should not import time", tt.nullable)
+		}
+	}
+}
+
+func TestGenStructNaming(t *testing.T) {
+	db := openSQLite(t, `create table t_user_account (id integer primary key, name text not null);
+create table order_item (id integer primary key);`)
+
+	savePath := genModel(t, func(savePath string) error {
+		return db.GenStructByTable(SQLite, "", savePath, false, WithPackage("dao"), WithTrimPrefix("x_", "t_"), WithFilePattern("{table}_gen"))
+	})
+
+	// 去掉前缀的表名用于结构体名和文件名，TableName仍返回原表名
+	user := readFile(t, filepath.Join(savePath, "user_account_gen.go"))
+	for _, want := range []string{"package dao", "type UserAccount struct", `return "t_user_account"`} {
+		if !strings.Contains(user, want) {
+			t.Errorf("user_account_gen.go does not contain %q:\n%s", want, user)
+		}
+	}
+	// 没有匹配前缀的表名不变
+	if item := readFile(t, filepath.Join(savePath, "order_item_gen.go")); !strings.Contains(item, "type OrderItem struct") {
+		t.Errorf("unexpected order_item_gen.go:\n%s", item)
+	}
+
+	// 默认包名为保存路径的最后一级目录名
+	savePath = genModel(t, func(savePath string) error {
+		return db.GenStructByTable(SQLite, "", savePath, false, WithTables("order_item"))
+	})
+	if item := readFile(t, filepath.Join(savePath, "order_item.go")); !strings.Contains(item, "package model") {
+		t.Errorf("unexpected order_item.go:\n%s", item)
+	}
+}
+
+func TestGenStructWithTemplate(t *testing.T) {
+	db := openSQLite(t, `create table t_user_account (
+	id INTEGER primary key autoincrement,
+	nickname varchar(32),
+	level INTEGER not null default 1
+);`)
+
+	dir := t.TempDir()
+	templates := map[string]string{
+		"dao.tpl": `package {{.Package}}
+
+// {{.Name}} {{plural .Name}} {{snake .Name}} {{lowerCamel .Name}}
+const {{lowerCamel .Name}}Table = "{{.Table}}"
+{{range .Fields}}
+// {{.CamelName}} {{.Name}} {{.SQLType}} default={{.Default}} nullable={{.Nullable}} pk={{.PrimaryKey}} autoincr={{.AutoIncrement}}{{end}}
+`,
+		"keys.tpl": `// Code generated by hand. DO NOT EDIT.
+
+package {{.Package}}
+
+var {{lowerCamel .Name}}Keys = []string{ {{range .PrimaryKeys}}"{{.Name}}",{{end}} }
+`,
+	}
+	for name, content := range templates {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savePath := genModel(t, func(savePath string) error {
+		return db.GenStructByTable(SQLite, "", savePath, false, WithTemplate(dir), WithTrimPrefix("t_"), WithFilePattern("{table}_gen.go"))
+	})
+
+	// 目录中的每个模板生成<表名>_<模板名>，没有头部注释时补上
+	dao := readFile(t, filepath.Join(savePath, "user_account_dao_gen.go"))
+	for _, want := range []string{
+		"// Code generated by esql. DO NOT EDIT. package model",
+		"// UserAccount UserAccounts user_account userAccount",
+		`const userAccountTable = "t_user_account"`,
+		"// ID id INTEGER default= nullable=false pk=true autoincr=true",
+		"// Nickname nickname varchar(32) default= nullable=true pk=false autoincr=false",
+		"// Level level INTEGER default=1 nullable=false pk=false autoincr=false",
+	} {
+		if !strings.Contains(dao, want) {
+			t.Errorf("user_account_dao_gen.go does not contain %q:\n%s", want, dao)
+		}
+	}
+	keys := readFile(t, filepath.Join(savePath, "user_account_keys_gen.go"))
+	if !strings.HasPrefix(keys, "// Code generated by hand. DO NOT EDIT. package model") || !strings.Contains(keys, `var userAccountKeys = []string{"id"}`) {
+		t.Errorf("unexpected user_account_keys_gen.go:\n%s", keys)
+	}
+
+	// 单个模板文件替换内置模板
+	savePath = genModel(t, func(savePath string) error {
+		return db.GenStructByTable(SQLite, "", savePath, false, WithTemplate(filepath.Join(dir, "dao.tpl")))
+	})
+	if dao = readFile(t, filepath.Join(savePath, "t_user_account.go")); !strings.Contains(dao, "const tUserAccountTable") {
+		t.Errorf("unexpected t_user_account.go:\n%s", dao)
+	}
+
+	if err := db.GenStructByTable(SQLite, "", t.TempDir(), false, WithTemplate(t.TempDir())); err == nil || !strings.Contains(err.Error(), "no *.tpl files") {
+		t.Errorf("expected an error for an empty template directory, got %v", err)
+	}
+	bad := filepath.Join(dir, "bad.tpl")
+	if err := os.WriteFile(bad, []byte("package {{.Package"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GenStructByTable(SQLite, "", t.TempDir(), false, WithTemplate(bad)); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestGenStructWithTags(t *testing.T) {
+	db := openSQLite(t, `create table account (
+	user_id integer primary key,
+	NickName text,
+	api_key text not null
+);`)
+
+	tests := []struct {
+		hasTag  bool
+		tagCase TagCase
+		want    []string
+	}{
+		{false, TagCaseOriginal, []string{
+			"UserID int64 `json:\"user_id\" form:\"user_id\"`",
+			"NickName string `esql:\"NickName\" json:\"NickName,omitempty\" form:\"NickName\"`",
+			"APIKey string `json:\"api_key\" form:\"api_key\"`",
+		}},
+		{true, TagCaseSnake, []string{
+			"UserID int64 `esql:\"user_id,pk,autoincr\" json:\"user_id\" form:\"user_id\"`",
+			"NickName string `esql:\"NickName\" json:\"nick_name,omitempty\" form:\"nick_name\"`",
+			"APIKey string `esql:\"api_key\" json:\"api_key\" form:\"api_key\"`",
+		}},
+		{false, TagCaseCamel, []string{
+			"UserID int64 `json:\"userId\" form:\"userId\"`",
+			"NickName string `esql:\"NickName\" json:\"nickName,omitempty\" form:\"nickName\"`",
+			"APIKey string `json:\"apiKey\" form:\"apiKey\"`",
+		}},
+	}
+	for _, tt := range tests {
+		savePath := genModel(t, func(savePath string) error {
+			return db.GenStructByTable(SQLite, "", savePath, tt.hasTag, WithTags("json", "form"), WithTagCase(tt.tagCase))
+		})
+
+		src := readFile(t, filepath.Join(savePath, "account.go"))
+		for _, want := range tt.want {
+			if !strings.Contains(src, want) {
+				t.Errorf("tag case %d: account.go does not contain %q:\n%s", tt.tagCase, want, src)
+			}
+		}
+	}
+}
+
+func TestGenStructComments(t *testing.T) {
+	ddls := map[string]string{
+		Mysql: "create table `user` (\n" +
+			"  `id` bigint unsigned not null auto_increment comment 'user id',\n" +
+			"  `name` varchar(64) not null comment 'first line\\nsecond line',\n" +
+			"  `email` varchar(64) not null,\n" +
+			"  primary key (`id`)\n" +
+			") comment='registered users';",
+		Postgres: `create table "user" (id bigserial primary key, name varchar(64) not null, email varchar(64) not null);
+comment on table "user" is 'registered users';
+comment on column "user".id is 'user id';
+comment on column "user".name is 'first line
+second line';`,
+	}
+	for mode, ddl := range ddls {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "schema.sql"), []byte(ddl), 0644); err != nil {
+			t.Fatal(err)
+		}
+		savePath := genModel(t, func(savePath string) error {
+			return GenStructByDDL(mode, dir, savePath, false)
+		})
+
+		data, err := os.ReadFile(filepath.Join(savePath, "user.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 多行注释的每一行都有注释符，没有注释的字段不生成注释
+		for _, want := range []string{
+			"// User registered users\ntype User struct {\n",
+			"\t// user id\n\tID ",
+			"\t// first line\n\t// second line\n\tName ",
+			"second line\n\tName  string\n\tEmail string\n}",
+		} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: user.go does not contain %q:\n%s", mode, want, data)
+			}
+		}
+	}
+}
+
+func TestGenStructInvalidOutput(t *testing.T) {
+	db := openSQLite(t, "create table account (id integer primary key);")
+
+	dir := t.TempDir()
+	templates := map[string]string{
+		"syntax.tpl":  "package {{.Package}}\n\nfunc {{.Name}}( {\n",
+		"execute.tpl": "package {{.Package}}\n\n// {{.Missing}}\n",
+	}
+	savePath := genModel(t, func(string) error { return nil })
+	existing := filepath.Join(savePath, "account.go")
+	if err := os.WriteFile(existing, []byte(generatedHeader+"\n\npackage model\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range templates {
+		tpl := filepath.Join(dir, name)
+		if err := os.WriteFile(tpl, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := db.GenStructByTable(SQLite, "", savePath, false, WithTemplate(tpl))
+		if err == nil || !strings.Contains(err.Error(), "table account") {
+			t.Fatalf("%s: expected an error with the table name, got %v", name, err)
+		}
+	}
+
+	// 出错时不写入，已有的文件保持不变，也不留下临时文件
+	files, err := os.ReadDir(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "account.go" {
+		t.Fatalf("unexpected files: %v", files)
+	}
+	if src := readFile(t, existing); src != generatedHeader+" package model" {
+		t.Fatalf("account.go changed: %s", src)
+	}
+
+	// 生成的代码在写入前格式化
+	messy := filepath.Join(dir, "messy.tpl")
+	if err = os.WriteFile(messy, []byte("package   {{.Package}}\nvar   {{lowerCamel .Name}}Table=\"{{.Table}}\""), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.GenStructByTable(SQLite, "", savePath, false, WithTemplate(messy)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := generatedHeader + "\n\npackage model\n\nvar accountTable = \"account\"\n"; string(data) != want {
+		t.Fatalf("unexpected account.go:\n%s", data)
+	}
+}
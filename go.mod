@@ -1,3 +1,8 @@
 module github.com/cyj19/esql
 
 go 1.16
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.10
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,399 @@
+package esql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FieldMeta describes how a struct field is mapped to a column.
+// (结构体字段与列的映射信息)
+type FieldMeta struct {
+	// Go field name, Author.ID for nested struct fields (字段名，嵌套结构体的字段为Author.ID)
+	Name string
+	// Column name (列名)
+	Column string
+	// Index path of the field, embedded structs are flattened (字段的索引路径，嵌入的结构体会展开)
+	Index []int
+	// Primary key, the pk option (主键)
+	PK bool
+	// Auto increment column, the autoincr option (自增列)
+	AutoIncr bool
+	// Excluded from writes, the readonly option (不写入)
+	ReadOnly bool
+	// Skipped on insert when zero, the omitempty option (零值时插入跳过)
+	OmitEmpty bool
+	// Stored as JSON, the json option (以JSON存储)
+	JSON bool
+	// Nested struct field only matched by aliases such as author.id in joins, not a column of the table
+	// (只能通过author.id等别名匹配的嵌套结构体字段，不是表中的列)
+	Alias bool
+	// 标签指定了列名，字段名不再用于匹配列
+	tagged bool
+	// 路径上指针结构体字段的索引长度，由外到内，如Author *User中的字段为[1]
+	ptrs []int
+}
+
+// Meta is the mapping of a struct type, it is parsed once per type and cached, StructMeta returns a copy.
+// (结构体类型的映射信息，每个类型只解析一次并缓存，StructMeta返回副本)
+type Meta struct {
+	Type   reflect.Type
+	Fields []*FieldMeta
+	// 列名及别名
+	byColumn map[string]*FieldMeta
+	// 小写的列名和字段名，用于不区分大小写的匹配
+	byLower map[string]*FieldMeta
+}
+
+// StructMeta returns a copy of the mapping of a struct type or a pointer to struct type,
+// modifying it doesn't affect the cached mapping used by queries.
+// (获取结构体或结构体指针类型映射信息的副本，修改副本不影响查询使用的缓存)
+/*
+	meta, err := esql.StructMeta(reflect.TypeOf(User{}))
+*/
+func StructMeta(t reflect.Type) (*Meta, error) {
+	if t == nil || Deref(t).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only accepts structs; got %v", t)
+	}
+
+	return getStructMeta(Deref(t)).clone(), nil
+}
+
+// 深拷贝映射信息，索引中的字段指向拷贝后的字段
+func (m *Meta) clone() *Meta {
+	out := &Meta{
+		Type:     m.Type,
+		Fields:   make([]*FieldMeta, len(m.Fields)),
+		byColumn: make(map[string]*FieldMeta, len(m.byColumn)),
+		byLower:  make(map[string]*FieldMeta, len(m.byLower)),
+	}
+
+	fields := make(map[*FieldMeta]*FieldMeta, len(m.Fields))
+	for i, f := range m.Fields {
+		c := *f
+		c.Index = append([]int(nil), f.Index...)
+		out.Fields[i] = &c
+		fields[f] = &c
+	}
+	for key, f := range m.byColumn {
+		out.byColumn[key] = fields[f]
+	}
+	for key, f := range m.byLower {
+		out.byLower[key] = fields[f]
+	}
+
+	return out
+}
+
+// Field returns the field mapped to the column, or nil.
+// (获取列对应的字段，没有时返回nil)
+func (m *Meta) Field(column string) *FieldMeta {
+	return m.byColumn[column]
+}
+
+// PrimaryKeys returns the fields with the pk option.
+// (获取主键字段)
+func (m *Meta) PrimaryKeys() []*FieldMeta {
+	var out []*FieldMeta
+	for _, f := range m.Fields {
+		if f.PK && !f.Alias {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+// AutoIncrement returns the field with the autoincr option, or nil.
+// (获取自增字段，没有时返回nil)
+func (m *Meta) AutoIncrement() *FieldMeta {
+	for _, f := range m.Fields {
+		if f.AutoIncr && !f.Alias {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// InsertFields returns the fields written by an insert of v, readonly fields are excluded,
+// omitempty and autoincr fields are excluded when zero.
+// (获取插入v时写入的字段，不包括readonly字段，以及零值的omitempty和autoincr字段)
+func (m *Meta) InsertFields(v reflect.Value) []*FieldMeta {
+	v = reflect.Indirect(v)
+	out := make([]*FieldMeta, 0, len(m.Fields))
+	for _, f := range m.Fields {
+		if !f.Writable() {
+			continue
+		}
+		if f.OmitEmpty || f.AutoIncr {
+			if value, ok := readFieldByIndex(v, f.Index); !ok || value.IsZero() {
+				continue
+			}
+		}
+
+		out = append(out, f)
+	}
+
+	return out
+}
+
+// UpdateFields returns the fields written by an update, readonly, pk and autoincr fields are excluded.
+// (获取更新时写入的字段，不包括readonly、主键和自增字段)
+func (m *Meta) UpdateFields() []*FieldMeta {
+	out := make([]*FieldMeta, 0, len(m.Fields))
+	for _, f := range m.Fields {
+		if f.Writable() && !f.PK && !f.AutoIncr {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+// Writable reports whether the field is a column written by inserts and updates.
+// (字段是否写入)
+func (f *FieldMeta) Writable() bool {
+	return !f.ReadOnly && !f.Alias
+}
+
+// ValueOf returns the value of the field in struct v for writing, JSON fields are marshalled.
+// (获取结构体v中字段用于写入的值，json字段会序列化)
+func (f *FieldMeta) ValueOf(v reflect.Value) interface{} {
+	value, ok := readFieldByIndex(reflect.Indirect(v), f.Index)
+	switch {
+	case !ok:
+		return nil
+	case f.JSON:
+		return jsonValue{value}
+	default:
+		return value.Interface()
+	}
+}
+
+// map[reflect.Type]*Meta
+var structMetaCache sync.Map
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// 获取结构体类型的元数据，t需为结构体类型
+func getStructMeta(t reflect.Type) *Meta {
+	if meta, ok := structMetaCache.Load(t); ok {
+		return meta.(*Meta)
+	}
+
+	meta := &Meta{Type: t, byColumn: make(map[string]*FieldMeta), byLower: make(map[string]*FieldMeta)}
+	meta.parse(t, nil, nil, "", nil, false, map[reflect.Type]bool{t: true})
+	// 先匹配字段名，再匹配列名，标签指定了列名的字段不按字段名匹配
+	for _, f := range meta.Fields {
+		if f.tagged {
+			continue
+		}
+		if key := strings.ToLower(f.Name); meta.byLower[key] == nil {
+			meta.byLower[key] = f
+		}
+	}
+	for key, f := range meta.byColumn {
+		if key = strings.ToLower(key); meta.byLower[key] == nil {
+			meta.byLower[key] = f
+		}
+	}
+	actual, _ := structMetaCache.LoadOrStore(t, meta)
+	return actual.(*Meta)
+}
+
+// 是否为作为单个列扫描的值类型，即time.Time和实现了sql.Scanner的类型，只实现driver.Valuer的结构体按字段映射
+func isValueType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+
+	return t.Implements(scannerType) || reflect.PtrTo(t).Implements(scannerType)
+}
+
+// 是否作为单个列扫描的类型，而不是按字段映射的行
+func isScalarType(t reflect.Type) bool {
+	if isValueType(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	case reflect.Slice:
+		// []byte
+		return t.Elem().Kind() == reflect.Uint8
+	default:
+		return false
+	}
+}
+
+// 解析结构体字段，ptrs为路径上的指针结构体字段，name为字段名前缀，prefixes为列名前缀，
+// visiting为正在解析的嵌套结构体，避免循环引用
+func (m *Meta) parse(t reflect.Type, index, ptrs []int, name string, prefixes []string, alias bool, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// 未导出的字段不能赋值，与encoding/json一样展开未导出的嵌入结构体（非指针）中导出的字段
+		unexported := field.PkgPath != ""
+		if unexported && (!field.Anonymous || field.Type.Kind() != reflect.Struct) {
+			continue
+		}
+
+		column, options := parseTag(field)
+		if column == "-" {
+			continue
+		}
+
+		path := make([]int, len(index)+1)
+		copy(path, index)
+		path[len(index)] = i
+
+		base := Deref(field.Type)
+		_, isJSON := options["json"]
+		nested := base.Kind() == reflect.Struct && !isValueType(base) && !isJSON
+
+		subPtrs := ptrs
+		if nested && field.Type.Kind() == reflect.Ptr {
+			subPtrs = append(append([]int(nil), ptrs...), len(path))
+		}
+
+		// 展开嵌入的结构体
+		if field.Anonymous && nested {
+			m.parse(base, path, subPtrs, name, prefixes, alias, visiting)
+			continue
+		}
+		if unexported {
+			continue
+		}
+
+		tagged := len(column) > 0
+		if !tagged {
+			// 没标签，默认字段名下划线格式
+			column = ConvertCamelToSnake(field.Name)
+		}
+
+		// 嵌套的结构体，列名为prefix选项指定的前缀，或者author.id、author__id格式的别名
+		if nested {
+			if visiting[base] {
+				continue
+			}
+
+			subAlias := alias
+			var subPrefixes []string
+			if prefix, ok := options["prefix"]; ok {
+				subPrefixes = joinPrefixes(prefixes, prefix)
+			} else {
+				subAlias = true
+				subPrefixes = joinPrefixes(prefixes, column+".", column+"__")
+			}
+
+			visiting[base] = true
+			m.parse(base, path, subPtrs, name+field.Name+".", subPrefixes, subAlias, visiting)
+			delete(visiting, base)
+			continue
+		}
+
+		columns := joinPrefixes(prefixes, column)
+		_, pk := options["pk"]
+		_, autoIncr := options["autoincr"]
+		_, readOnly := options["readonly"]
+		_, omitEmpty := options["omitempty"]
+		f := &FieldMeta{
+			Name:      name + field.Name,
+			Column:    columns[0],
+			Index:     path,
+			PK:        pk,
+			AutoIncr:  autoIncr,
+			ReadOnly:  readOnly,
+			OmitEmpty: omitEmpty,
+			JSON:      isJSON,
+			Alias:     alias,
+			tagged:    tagged,
+			ptrs:      ptrs,
+		}
+		m.Fields = append(m.Fields, f)
+		for _, c := range columns {
+			m.byColumn[c] = f
+		}
+	}
+}
+
+// 组合列名前缀
+func joinPrefixes(prefixes []string, names ...string) []string {
+	if len(prefixes) == 0 {
+		return names
+	}
+
+	out := make([]string, 0, len(prefixes)*len(names))
+	for _, prefix := range prefixes {
+		for _, name := range names {
+			out = append(out, prefix+name)
+		}
+	}
+
+	return out
+}
+
+// 按列的顺序获取对应的字段，先精确匹配标签和下划线格式的字段名，再不区分大小写匹配，
+// 每个字段只对应第一个匹配的列，没有对应字段或字段已被匹配的列为nil
+func (m *Meta) match(columns []string) []*FieldMeta {
+	fields := make([]*FieldMeta, len(columns))
+	matched := make(map[*FieldMeta]bool, len(columns))
+	for i, column := range columns {
+		if f, ok := m.byColumn[column]; ok && !matched[f] {
+			fields[i] = f
+			matched[f] = true
+		}
+	}
+	for i, column := range columns {
+		if fields[i] != nil {
+			continue
+		}
+		if f := m.byLower[strings.ToLower(column)]; f != nil && !matched[f] {
+			fields[i] = f
+			matched[f] = true
+		}
+	}
+
+	return fields
+}
+
+// 按索引路径读取字段，路径上有nil的指针时返回false
+func readFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+// 按索引路径获取字段，路径上为nil的指针会被初始化
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v
+}
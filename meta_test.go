@@ -0,0 +1,254 @@
+package esql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStructMetaReturnsCopy(t *testing.T) {
+	type author struct {
+		ID   int64
+		Name string
+	}
+	type post struct {
+		ID     int64 `esql:"id,pk,autoincr"`
+		Title  string
+		Author author
+	}
+
+	meta, err := StructMeta(reflect.TypeOf(&post{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Field("title") != meta.Fields[1] || meta.PrimaryKeys()[0] != meta.Fields[0] {
+		t.Fatal("the copy should index its own fields")
+	}
+
+	meta.Fields[1].Column = "changed"
+	meta.Fields[1].ReadOnly = true
+	meta.Fields[2].Index[0] = 0
+	meta.Fields = meta.Fields[:1]
+
+	cached := getStructMeta(reflect.TypeOf(post{}))
+	if len(cached.Fields) != 4 {
+		t.Fatalf("cached fields changed: %d", len(cached.Fields))
+	}
+	title := cached.Field("title")
+	if title == nil || title.Column != "title" || title.ReadOnly {
+		t.Fatalf("cached field changed: %+v", title)
+	}
+	if index := cached.Fields[2].Index; !reflect.DeepEqual(index, []int{2, 0}) {
+		t.Fatalf("cached index changed: %v", index)
+	}
+}
+
+// 只实现driver.Valuer，按字段映射
+type valuerOnly struct {
+	ID   int64
+	Name string
+}
+
+func (v valuerOnly) Value() (driver.Value, error) {
+	return v.Name, nil
+}
+
+func TestIsScalarType(t *testing.T) {
+	tests := []struct {
+		typ  reflect.Type
+		want bool
+	}{
+		{reflect.TypeOf(time.Time{}), true},
+		{reflect.TypeOf(sql.NullString{}), true},
+		{reflect.TypeOf(sql.NullTime{}), true},
+		{reflect.TypeOf(int64(0)), true},
+		{reflect.TypeOf([]byte(nil)), true},
+		{reflect.TypeOf(valuerOnly{}), false},
+		{reflect.TypeOf(struct{ ID int64 }{}), false},
+	}
+	for _, tt := range tests {
+		if got := isScalarType(tt.typ); got != tt.want {
+			t.Errorf("isScalarType(%v) = %v, want %v", tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestQueryRowValuerOnlyStruct(t *testing.T) {
+	db := openSQLite(t, userDDL)
+
+	var u valuerOnly
+	if err := db.QueryRow(&u, "select id, name from user where id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 || u.Name != "tom" {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	type post struct {
+		Title  string
+		Author valuerOnly `esql:"author,prefix=author_"`
+	}
+	var p post
+	if err := db.QueryRow(&p, "select 'hello' as title, id as author_id, name as author_name from user where id=?", 2); err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "hello" || p.Author.ID != 2 || p.Author.Name != "jerry" {
+		t.Fatalf("unexpected post: %+v", p)
+	}
+}
+
+type auditBase struct {
+	ID        int64  `esql:"id,pk,autoincr"`
+	CreatedAt string `esql:"created_at,readonly"`
+	note      string
+}
+
+// 未导出的嵌入值类型不是结构体字段展开，不能赋值
+type unexportedTime time.Time
+
+type embedUser struct {
+	auditBase
+	unexportedTime
+	Name string
+}
+
+func TestUnexportedEmbeddedStruct(t *testing.T) {
+	if got := RawFieldNames(&embedUser{}); !reflect.DeepEqual(got, []string{"`id`", "`created_at`", "`name`"}) {
+		t.Fatalf("unexpected field names: %v", got)
+	}
+	u := embedUser{auditBase: auditBase{ID: 3, note: "x"}, Name: "tom"}
+	if got := RawFieldValues(&u, "`created_at`"); !reflect.DeepEqual(got, []interface{}{int64(3), "tom"}) {
+		t.Fatalf("unexpected field values: %v", got)
+	}
+
+	db := openSQLite(t, userDDL)
+	var users []embedUser
+	if err := db.QueryRows(&users, "select id, name, created_at from user order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].ID != 2 || users[1].Name != "jerry" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	spike := embedUser{Name: "spike"}
+	if _, err := db.Insert(context.Background(), "user", &spike); err != nil {
+		t.Fatal(err)
+	}
+	if spike.ID != 3 {
+		t.Fatalf("autoincr not set: %d", spike.ID)
+	}
+}
+
+const postDDL = userDDL + `
+create table post (
+	id integer primary key autoincrement,
+	title text not null,
+	author_id integer not null
+);
+insert into post (title, author_id) values ('hello', 2), ('orphan', 9);`
+
+type joinAuthor struct {
+	ID    int64
+	Name  string
+	Email sql.NullString
+}
+
+func TestQueryJoinAliases(t *testing.T) {
+	db := openSQLite(t, postDDL)
+
+	type post struct {
+		ID     int64
+		Title  string
+		Author joinAuthor
+	}
+
+	var p post
+	query := `select p.id, p.title, u.id as "author.id", u.name as "author.name" from post p join user u on u.id = p.author_id where p.id=?`
+	if err := db.QueryRow(&p, query, 1); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 1 || p.Title != "hello" || p.Author.ID != 2 || p.Author.Name != "jerry" {
+		t.Fatalf("unexpected post: %+v", p)
+	}
+
+	var posts []post
+	query = `select p.id, p.title, u.id as author__id, u.name as author__name from post p join user u on u.id = p.author_id`
+	if err := db.QueryRows(&posts, query); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].Author.ID != 2 || posts[0].Author.Name != "jerry" {
+		t.Fatalf("unexpected posts: %+v", posts)
+	}
+
+	// 别名字段不要求有对应的列
+	meta := getStructMeta(reflect.TypeOf(post{}))
+	if f := meta.Field("author__name"); f == nil || !f.Alias || f != meta.Field("author.name") {
+		t.Fatalf("unexpected alias field: %+v", f)
+	}
+	if err := db.QueryRow(&p, "select id, title from post where id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryPrefix(t *testing.T) {
+	db := openSQLite(t, postDDL)
+
+	type post struct {
+		Title  string
+		Author *joinAuthor `esql:"author,prefix=author_"`
+	}
+
+	var p post
+	query := "select p.title, u.id as author_id, u.name as author_name, u.email as author_email from post p join user u on u.id = p.author_id"
+	if err := db.QueryRow(&p, query); err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "hello" || p.Author == nil || p.Author.ID != 2 || p.Author.Name != "jerry" || p.Author.Email.Valid {
+		t.Fatalf("unexpected post: %+v", p)
+	}
+
+	// prefix选项的字段是表中的列，严格模式下必须有对应的列
+	err := db.QueryRow(&p, "select title from post")
+	if mismatch, ok := err.(*MismatchError); !ok || len(mismatch.Fields) != 3 {
+		t.Fatalf("expected mismatch of author fields, got %v", err)
+	}
+}
+
+func TestQueryLeftJoinNilPointer(t *testing.T) {
+	db := openSQLite(t, postDDL)
+
+	type post struct {
+		ID     int64
+		Title  string
+		Author *joinAuthor
+	}
+
+	query := `select p.id, p.title, u.id as "author.id", u.name as "author.name", u.email as "author.email" ` +
+		`from post p left join user u on u.id = p.author_id order by p.id`
+	var posts []*post
+	if err := db.QueryRows(&posts, query); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("unexpected posts: %+v", posts)
+	}
+	if a := posts[0].Author; a == nil || a.ID != 2 || a.Name != "jerry" || a.Email.Valid {
+		t.Fatalf("unexpected author: %+v", a)
+	}
+	// 没有匹配的行，非空类型的字段也不会扫描出错
+	if posts[1].Title != "orphan" || posts[1].Author != nil {
+		t.Fatalf("author should be nil: %+v", posts[1].Author)
+	}
+
+	// 已有的指针也置为nil
+	p := post{Author: &joinAuthor{ID: 1}}
+	if err := db.QueryRow(&p, query+" desc"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "orphan" || p.Author != nil {
+		t.Fatalf("author should be nil: %+v", p.Author)
+	}
+}
@@ -1,6 +1,9 @@
 package esql
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -25,129 +28,327 @@ var (
 	ErrRecordNotFound = errors.New("record not found")
 )
 
+// MismatchError is returned in strict mode when some fields of the destination are not matched by any column,
+// the unmatched columns are listed to help finding misspelled names.
+// errors.Is(err, ErrNotMatchDestination) reports true for it.
+// (严格模式下存在没有对应列的字段时返回的错误，同时列出没有对应字段的列，便于排查拼写错误)
+type MismatchError struct {
+	Type reflect.Type
+	// 没有对应字段的列
+	Columns []string
+	// 没有对应列的字段
+	Fields []string
+}
+
+func (e *MismatchError) Error() string {
+	msg := fmt.Sprintf("%s: fields %s have no column in %s", ErrNotMatchDestination, strings.Join(e.Fields, ", "), e.Type)
+	if len(e.Columns) > 0 {
+		msg += fmt.Sprintf(" (unmatched columns: %s)", strings.Join(e.Columns, ", "))
+	}
+
+	return msg
+}
+
+func (e *MismatchError) Is(target error) bool {
+	return target == ErrNotMatchDestination
+}
+
 const tagName = "esql"
 
 // sql.Rows默认已实现
 type rowsScanner interface {
 	Columns() ([]string, error)
+	ColumnTypes() ([]*sql.ColumnType, error)
 	Err() error
 	Next() bool
 	Scan(v ...interface{}) error
 }
 
-// 通过tag获取字段名称和字段值
-func getTaggedFieldValueMap(v reflect.Value) (map[string]interface{}, error) {
-	rt := Deref(v.Type())
-	size := rt.NumField()
-	result := make(map[string]interface{}, size)
-
-	for i := 0; i < size; i++ {
-		field := rt.Field(i)
-		// 判断是否是嵌入的结构体
-		if field.Anonymous && Deref(field.Type).Kind() == reflect.Struct {
-			inner, err := getTaggedFieldValueMap(reflect.Indirect(v).Field(i))
-			if err != nil {
-				return nil, err
-			}
+// 获取字段的地址用于扫描，指针字段扫描到指针的地址，列为NULL时指针为nil，否则由database/sql分配
+func getValueInterface(value reflect.Value) (interface{}, error) {
+	if !value.CanAddr() || !value.Addr().CanInterface() {
+		return nil, ErrNotReadableValue
+	}
+
+	return value.Addr().Interface(), nil
+}
+
+// 按列名匹配结构体的字段，没有对应字段或对应的字段已被前面的列匹配的列会被丢弃，严格模式下每个字段都必须有对应的列
+func matchStructFields(t reflect.Type, columns []string, strict bool) ([]*FieldMeta, error) {
+	meta := getStructMeta(t)
+	fields := meta.match(columns)
+	if !strict {
+		return fields, nil
+	}
+
+	matched := make(map[*FieldMeta]bool, len(fields))
+	mismatch := &MismatchError{Type: t}
+	for i, field := range fields {
+		if field == nil {
+			mismatch.Columns = append(mismatch.Columns, columns[i])
+		} else {
+			matched[field] = true
+		}
+	}
+	for _, field := range meta.Fields {
+		// 嵌套结构体的别名字段只在关联查询时使用，不要求有对应的列
+		if !matched[field] && !field.Alias {
+			mismatch.Fields = append(mismatch.Fields, field.Name)
+		}
+	}
 
-			for key, val := range inner {
-				result[key] = val
+	if len(mismatch.Fields) > 0 {
+		return nil, mismatch
+	}
+
+	return fields, nil
+}
+
+// 将结构体字段映射到切片，没有对应字段的列扫描到丢弃的变量，values可以在多行之间复用
+func mapStructFieldsIntoSlice(v reflect.Value, fields []*FieldMeta, values []interface{}) error {
+	v = reflect.Indirect(v)
+	for i, field := range fields {
+		if field == nil {
+			if values[i] == nil {
+				var anonymous interface{}
+				values[i] = &anonymous
 			}
+			continue
+		}
 
+		// 指针嵌套结构体的字段先扫描到临时变量，由setPointerFields赋值
+		if len(field.ptrs) > 0 {
+			if values[i] == nil {
+				values[i] = newPointerFieldValue(v.Type(), field)
+			}
 			continue
 		}
 
-		key := parseTagName(field)
-		if len(key) == 0 {
-			// 没标签，默认字段名下划线格式
-			key = ConvertCamelToSnake(field.Name)
+		value := fieldByIndex(v, field.Index)
+		if field.JSON {
+			values[i] = jsonValue{value}
+			continue
 		}
 
-		valueField := reflect.Indirect(v).Field(i)
-		valueData, err := getValueInterface(valueField)
+		valueData, err := getValueInterface(value)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		result[key] = valueData
+		values[i] = valueData
 	}
 
-	return result, nil
+	return nil
 }
 
-func getValueInterface(value reflect.Value) (interface{}, error) {
-	switch value.Kind() {
-	case reflect.Ptr:
-		if !value.CanInterface() {
-			return nil, ErrNotReadableValue
+// 指针嵌套结构体字段的临时变量，json字段为*interface{}保存原始值，其他字段为**T，NULL时为nil
+func newPointerFieldValue(t reflect.Type, field *FieldMeta) interface{} {
+	if field.JSON {
+		return new(interface{})
+	}
+
+	return reflect.New(reflect.PtrTo(t.FieldByIndex(field.Index).Type)).Interface()
+}
+
+// 临时变量扫描到的是否为NULL
+func isNullPointerFieldValue(value interface{}) bool {
+	if raw, ok := value.(*interface{}); ok {
+		return *raw == nil
+	}
+
+	return reflect.ValueOf(value).Elem().IsNil()
+}
+
+// 扫描后给指针嵌套结构体的字段赋值，所有列都为NULL的指针结构体保持nil，如LEFT JOIN没有匹配的行，
+// 否则初始化指针，为NULL的字段设为零值
+func setPointerFields(v reflect.Value, fields []*FieldMeta, values []interface{}) error {
+	v = reflect.Indirect(v)
+	// 有不为NULL的列的指针结构体
+	var notNull map[string]bool
+	for i, field := range fields {
+		if field == nil || len(field.ptrs) == 0 || isNullPointerFieldValue(values[i]) {
+			continue
 		}
 
-		if value.IsNil() {
-			// 返回value的实际类型
-			baseValueType := Deref(value.Type())
-			// 新建一个指向和value相同类型的新零值的指针，赋值给value
-			value.Set(reflect.New(baseValueType))
+		if notNull == nil {
+			notNull = make(map[string]bool)
 		}
+		for _, n := range field.ptrs {
+			notNull[fmt.Sprint(field.Index[:n])] = true
+		}
+	}
 
-		return value.Interface(), nil
-	default:
-		if !value.CanAddr() || !value.Addr().CanInterface() {
-			return nil, ErrNotReadableValue
+	for i, field := range fields {
+		if field == nil || len(field.ptrs) == 0 {
+			continue
+		}
+
+		// 由外到内第一个所有列都为NULL的指针结构体置为nil
+		nilAt := 0
+		for _, n := range field.ptrs {
+			if !notNull[fmt.Sprint(field.Index[:n])] {
+				nilAt = n
+				break
+			}
+		}
+		if nilAt > 0 {
+			ptr := fieldByIndex(v, field.Index[:nilAt])
+			ptr.Set(reflect.Zero(ptr.Type()))
+			continue
+		}
+
+		value := fieldByIndex(v, field.Index)
+		if field.JSON {
+			if err := (jsonValue{value}).Scan(*values[i].(*interface{})); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if src := reflect.ValueOf(values[i]).Elem(); src.IsNil() {
+			value.Set(reflect.Zero(value.Type()))
+		} else {
+			value.Set(src.Elem())
 		}
-		// 创建空值返回
-		return value.Addr().Interface(), nil
 	}
+
+	return nil
+}
+
+// json选项的字段，扫描时把列反序列化到字段，写入时把字段序列化为字符串
+type jsonValue struct {
+	v reflect.Value
+}
+
+func (j jsonValue) Scan(src interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case nil:
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	default:
+		return fmt.Errorf("cannot scan %T into json field of type %s", src, j.v.Type())
+	}
+
+	if !j.v.CanAddr() {
+		return ErrNotSettable
+	}
+	// NULL或空值时为零值
+	if len(data) == 0 {
+		j.v.Set(reflect.Zero(j.v.Type()))
+		return nil
+	}
+
+	return json.Unmarshal(data, j.v.Addr().Interface())
 }
 
-// 将结构体字段类型变量映射到切片
-func mapStructFieldsIntoSlice(v reflect.Value, columns []string, strict bool) ([]interface{}, error) {
-	fields := unwrapFields(v)
-	if strict && len(columns) < len(fields) {
-		return nil, ErrNotMatchDestination
+func (j jsonValue) Value() (driver.Value, error) {
+	switch j.v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		// nil写入NULL
+		if j.v.IsNil() {
+			return nil, nil
+		}
 	}
 
-	taggedMap, err := getTaggedFieldValueMap(v)
+	data, err := json.Marshal(j.v.Interface())
 	if err != nil {
 		return nil, err
 	}
 
-	values := make([]interface{}, len(columns))
-	if len(taggedMap) == 0 {
-		if len(fields) < len(values) {
-			return nil, ErrNotMatchDestination
-		}
+	// 使用字符串，PostgreSQL驱动会把[]byte作为bytea
+	return string(data), nil
+}
 
-		for i := 0; i < len(values); i++ {
-			valueField := fields[i]
-			valueData, err := getValueInterface(valueField)
-			if err != nil {
-				return nil, err
-			}
+var mapType = reflect.TypeOf(map[string]interface{}{})
 
-			values[i] = valueData
+// 获取列名和列是否为文本类型，文本类型的[]byte会转为string
+func textualColumns(scanner rowsScanner) ([]string, []bool, error) {
+	columns, err := scanner.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	types, err := scanner.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	textual := make([]bool, len(columns))
+	for i := range textual {
+		// 驱动不提供类型时按文本处理
+		textual[i] = true
+		if i < len(types) {
+			textual[i] = !isBinaryType(types[i].DatabaseTypeName())
 		}
-	} else {
-		for i, column := range columns {
-			if tagged, ok := taggedMap[column]; ok {
-				values[i] = tagged
-			} else {
-				var anonymous interface{}
-				values[i] = &anonymous
-			}
+	}
+
+	return columns, textual, nil
+}
+
+// 二进制类型的列保留[]byte
+func isBinaryType(name string) bool {
+	name = strings.ToUpper(name)
+	switch {
+	case strings.Contains(name, "BLOB"), strings.Contains(name, "BINARY"):
+		return true
+	case name == "BYTEA", name == "BIT", name == "VARBIT", name == "GEOMETRY":
+		return true
+	default:
+		return false
+	}
+}
+
+// 把当前行扫描到map，键为列名
+func scanMap(scanner rowsScanner, columns []string, textual []bool) (map[string]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := scanner.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		if b, ok := values[i].([]byte); ok && textual[i] {
+			m[column] = string(b)
+		} else {
+			m[column] = values[i]
 		}
 	}
 
-	return values, nil
+	return m, nil
 }
 
-func parseTagName(field reflect.StructField) string {
+// 解析标签，返回列名和选项，如esql:"author,prefix=author_"
+func parseTag(field reflect.StructField) (string, map[string]string) {
 	key := field.Tag.Get(tagName)
 	if len(key) == 0 {
-		return ""
+		return "", nil
+	}
+
+	parts := strings.Split(key, ",")
+	options := make(map[string]string, len(parts)-1)
+	for _, option := range parts[1:] {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) == 2 {
+			options[kv[0]] = kv[1]
+		} else {
+			options[kv[0]] = ""
+		}
 	}
 
-	options := strings.Split(key, ",")
-	return strings.TrimSpace(options[0])
+	return strings.TrimSpace(parts[0]), options
 }
 
 // 把单条数据scan到v
@@ -168,29 +369,54 @@ func unmarshalRow(v interface{}, scanner rowsScanner, strict bool) error {
 	// 获取具体类型
 	rte := reflect.TypeOf(v).Elem()
 	rve := rv.Elem()
-	switch rte.Kind() {
-	case reflect.Bool,
-		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Float32, reflect.Float64,
-		reflect.String:
+	// 基本类型、time.Time和实现了sql.Scanner的类型直接扫描，需在按种类判断之前
+	if isScalarType(rte) {
 		if !rve.CanSet() {
 			return ErrNotSettable
 		}
 
 		return scanner.Scan(v)
+	}
+
+	switch rte.Kind() {
 	case reflect.Struct:
 		columns, err := scanner.Columns()
 		if err != nil {
 			return err
 		}
 
-		values, err := mapStructFieldsIntoSlice(rve, columns, strict)
+		fields, err := matchStructFields(rte, columns, strict)
 		if err != nil {
 			return err
 		}
+
+		values := make([]interface{}, len(columns))
+		if err = mapStructFieldsIntoSlice(rve, fields, values); err != nil {
+			return err
+		}
 		// 扫描到结构体的每个字段值
-		return scanner.Scan(values...)
+		if err = scanner.Scan(values...); err != nil {
+			return err
+		}
+
+		return setPointerFields(rve, fields, values)
+	case reflect.Map:
+		if rte != mapType {
+			return ErrUnsupportedValueType
+		}
+
+		columns, textual, err := textualColumns(scanner)
+		if err != nil {
+			return err
+		}
+
+		m, err := scanMap(scanner, columns, textual)
+		if err != nil {
+			return err
+		}
+
+		rve.Set(reflect.ValueOf(m))
+		return nil
 	default:
 		return ErrUnsupportedValueType
 	}
@@ -232,35 +458,64 @@ func unmarshalRows(v interface{}, scanner rowsScanner, strict bool) error {
 		}
 
 		base := Deref(rte.Elem())
-		switch base.Kind() {
-		case reflect.Bool,
-			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-			reflect.Float32, reflect.Float64,
-			reflect.String:
+		if isScalarType(base) {
 			for scanner.Next() {
 				value := reflect.New(base)
 				if err := fillFn(value.Interface()); err != nil {
 					return err
 				}
 			}
+
+			return nil
+		}
+
+		switch base.Kind() {
 		case reflect.Struct:
 			columns, err := scanner.Columns()
 			if err != nil {
 				return err
 			}
 
+			// 字段只匹配一次，values在每行之间复用
+			fields, err := matchStructFields(base, columns, strict)
+			if err != nil {
+				return err
+			}
+
+			values := make([]interface{}, len(columns))
 			for scanner.Next() {
 				value := reflect.New(base)
-				values, err := mapStructFieldsIntoSlice(value, columns, strict)
-				if err != nil {
+				if err := mapStructFieldsIntoSlice(value, fields, values); err != nil {
 					return err
 				}
 
 				if err := scanner.Scan(values...); err != nil {
 					return err
 				}
+				if err := setPointerFields(value, fields, values); err != nil {
+					return err
+				}
+
+				appendFn(value)
+			}
+		case reflect.Map:
+			if base != mapType {
+				return ErrUnsupportedValueType
+			}
+
+			columns, textual, err := textualColumns(scanner)
+			if err != nil {
+				return err
+			}
+
+			for scanner.Next() {
+				m, err := scanMap(scanner, columns, textual)
+				if err != nil {
+					return err
+				}
 
+				value := reflect.New(base)
+				value.Elem().Set(reflect.ValueOf(m))
 				appendFn(value)
 			}
 		default:
@@ -273,39 +528,6 @@ func unmarshalRows(v interface{}, scanner rowsScanner, strict bool) error {
 	}
 }
 
-// 解析v，返回其所有字段
-func unwrapFields(v reflect.Value) []reflect.Value {
-	var fields []reflect.Value
-	indirect := reflect.Indirect(v)
-
-	for i := 0; i < indirect.NumField(); i++ {
-		child := indirect.Field(i)
-		if !child.CanSet() {
-			continue
-		}
-
-		if child.Kind() == reflect.Ptr && child.IsNil() {
-			baseValueType := Deref(child.Type())
-			child.Set(reflect.New(baseValueType))
-		}
-
-		child = reflect.Indirect(child)
-		childType := indirect.Type().Field(i)
-		// 如果tag值为"-"则忽略
-		tagValue := childType.Tag.Get(tagName)
-		if tagValue == "-" {
-			continue
-		}
-		if child.Kind() == reflect.Struct && childType.Anonymous {
-			fields = append(fields, unwrapFields(child)...)
-		} else {
-			fields = append(fields, child)
-		}
-	}
-
-	return fields
-}
-
 // Deref 取消引用类型，如果是指针类型，则返回其元素类型
 func Deref(t reflect.Type) reflect.Type {
 	for t.Kind() == reflect.Ptr {
@@ -0,0 +1,325 @@
+package esql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// 打开临时目录中的SQLite数据库并执行建表语句
+func openSQLite(t testing.TB, ddl string) *DB {
+	t.Helper()
+
+	db, err := Open(SQLite, filepath.Join(t.TempDir(), "test.db"), &logger{level: Disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.DB().Close()
+	})
+
+	if ddl != "" {
+		if _, err = db.DB().Exec(ddl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+const userDDL = `create table user (
+	id integer primary key autoincrement,
+	name text not null,
+	email text,
+	created_at text not null default ''
+);
+insert into user (name, email) values ('tom', 'tom@example.com'), ('jerry', null);`
+
+func TestQueryRowsDiscardsExtraColumns(t *testing.T) {
+	db := openSQLite(t, userDDL)
+
+	type user struct {
+		ID   int64
+		Name string
+	}
+
+	var users []user
+	if err := db.QueryRows(&users, "select * from user order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].ID != 1 || users[0].Name != "tom" || users[1].Name != "jerry" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	var one user
+	if err := db.QueryRow(&one, "select *, 1 as extra from user where id=?", 2); err != nil {
+		t.Fatal(err)
+	}
+	if one.Name != "jerry" {
+		t.Fatalf("unexpected user: %+v", one)
+	}
+}
+
+func TestQueryRowStrictMissingColumn(t *testing.T) {
+	db := openSQLite(t, userDDL)
+
+	type user struct {
+		ID       int64
+		Name     string
+		Nickname string
+	}
+
+	var u user
+	err := db.QueryRow(&u, "select id, name, email from user where id=?", 1)
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrNotMatchDestination) {
+		t.Fatalf("expected MismatchError, got %v", err)
+	}
+	if len(mismatch.Fields) != 1 || mismatch.Fields[0] != "Nickname" {
+		t.Fatalf("unexpected fields: %v", mismatch.Fields)
+	}
+	if len(mismatch.Columns) != 1 || mismatch.Columns[0] != "email" {
+		t.Fatalf("unexpected columns: %v", mismatch.Columns)
+	}
+
+	u.Nickname = "keep"
+	if err = db.WithStrict(false).QueryRow(&u, "select id, name from user where id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 || u.Name != "tom" || u.Nickname != "keep" {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+}
+
+func TestQueryRowNullPointerField(t *testing.T) {
+	db := openSQLite(t, userDDL)
+
+	type user struct {
+		ID    int64
+		Email *string
+	}
+
+	var users []user
+	if err := db.QueryRows(&users, "select id, email from user order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if users[0].Email == nil || *users[0].Email != "tom@example.com" || users[1].Email != nil {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+}
+
+// 10000行数据扫描到结构体切片，字段信息按类型缓存后每行只分配扫描值本身
+func BenchmarkQueryRows(b *testing.B) {
+	db := openSQLite(b, `create table report (
+	id integer primary key,
+	user_id integer not null,
+	title text not null,
+	amount real not null,
+	created_at text not null
+);
+with recursive seq(n) as (select 1 union all select n + 1 from seq where n < 10000)
+insert into report select n, n % 100, 'title ' || n, n * 1.5, '2021-01-01' from seq;`)
+
+	type report struct {
+		ID        int64
+		UserID    int64
+		Title     string
+		Amount    float64
+		CreatedAt string
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var rows []report
+		if err := db.QueryRows(&rows, "select id, user_id, title, amount, created_at from report"); err != nil {
+			b.Fatal(err)
+		}
+		if len(rows) != 10000 {
+			b.Fatalf("expected 10000 rows, got %d", len(rows))
+		}
+	}
+}
+
+func TestQueryRowTaggedFieldMatchesOnlyTag(t *testing.T) {
+	db := openSQLite(t, userDDL)
+
+	type user struct {
+		ID    int64
+		Title string `esql:"name"`
+	}
+
+	// title列不能按字段名匹配到Title
+	var u user
+	if err := db.QueryRow(&u, "select id, name, 'other' as title from user where id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.Title != "tom" {
+		t.Fatalf("unexpected title: %q", u.Title)
+	}
+
+	// 没有name列时严格模式报告Title没有对应的列
+	err := db.QueryRow(&u, "select id, name as title from user where id=?", 1)
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) || len(mismatch.Fields) != 1 || mismatch.Fields[0] != "Title" {
+		t.Fatalf("expected Title to be unmatched, got %v", err)
+	}
+
+	// 多个列对应同一字段时只使用第一个精确匹配的列
+	type account struct {
+		ID   int64
+		Name string
+	}
+	var a account
+	if err = db.QueryRow(&a, "select id, 'upper' as NAME, name from user where id=?", 2); err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "jerry" {
+		t.Fatalf("unexpected name: %q", a.Name)
+	}
+	if err = db.QueryRow(&a, "select id, 'first' as NAME, 'second' as Name from user where id=?", 2); err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "first" {
+		t.Fatalf("unexpected name: %q", a.Name)
+	}
+}
+
+func TestQueryMap(t *testing.T) {
+	db := openSQLite(t, `create table file (
+	id integer primary key,
+	name text,
+	data blob,
+	size real
+);
+insert into file values (1, cast('a.txt' as blob), x'0001', 1.5), (2, null, null, null);`)
+
+	var m map[string]interface{}
+	if err := db.QueryRow(&m, "select id, name, data, size from file where id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+	// TEXT列即使以BLOB存储也返回string，BLOB列保留[]byte
+	want := map[string]interface{}{"id": int64(1), "name": "a.txt", "data": []byte{0, 1}, "size": 1.5}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("got %#v, want %#v", m, want)
+	}
+
+	var rows []map[string]interface{}
+	if err := db.QueryRows(&rows, "select id, name, data, size from file order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || !reflect.DeepEqual(rows[0], want) {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+	if null := (map[string]interface{}{"id": int64(2), "name": nil, "data": nil, "size": nil}); !reflect.DeepEqual(rows[1], null) {
+		t.Fatalf("got %#v, want %#v", rows[1], null)
+	}
+
+	if err := db.QueryRow(&m, "select * from file where id=?", 3); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	rows = nil
+	if err := db.QueryRows(&rows, "select * from file where id=?", 3); err != nil || len(rows) != 0 {
+		t.Fatalf("expected no rows, got %v, %v", rows, err)
+	}
+}
+
+const profileDDL = `create table profile (
+	id integer primary key autoincrement,
+	settings text,
+	tags text,
+	address text
+);`
+
+type profileAddress struct {
+	City string `json:"city"`
+}
+
+type profile struct {
+	ID       int64                  `esql:"id,pk,autoincr"`
+	Settings map[string]interface{} `esql:"settings,json"`
+	Tags     []string               `esql:"tags,json"`
+	Address  profileAddress         `esql:"address,json"`
+}
+
+func TestJSONField(t *testing.T) {
+	db := openSQLite(t, profileDDL)
+	ctx := context.Background()
+
+	p := profile{
+		Settings: map[string]interface{}{"theme": "dark"},
+		Tags:     []string{"a", "b"},
+		Address:  profileAddress{City: "Shenzhen"},
+	}
+	values := RawFieldValues(&p, "`id`")
+	want := []interface{}{`{"theme":"dark"}`, `["a","b"]`, `{"city":"Shenzhen"}`}
+	for i, value := range values {
+		valuer, ok := value.(driver.Valuer)
+		if !ok {
+			t.Fatalf("value %d should be a driver.Valuer: %T", i, value)
+		}
+		if got, err := valuer.Value(); err != nil || got != want[i] {
+			t.Fatalf("value %d = %v, %v, want %v", i, got, err, want[i])
+		}
+	}
+
+	if _, err := db.Insert(ctx, "profile", &p); err != nil {
+		t.Fatal(err)
+	}
+	// nil的map和切片写入NULL
+	if _, err := db.Insert(ctx, "profile", &profile{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw []struct {
+		Settings sql.NullString
+		Tags     sql.NullString
+		Address  string
+	}
+	if err := db.QueryRows(&raw, "select settings, tags, address from profile order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 2 || raw[0].Settings.String != want[0] || raw[0].Tags.String != want[1] || raw[0].Address != want[2] {
+		t.Fatalf("unexpected stored values: %+v", raw)
+	}
+	if raw[1].Settings.Valid || raw[1].Tags.Valid || raw[1].Address != `{"city":""}` {
+		t.Fatalf("unexpected stored zero values: %+v", raw[1])
+	}
+
+	var got profile
+	if err := db.QueryRow(&got, "select * from profile where id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, profile{ID: 1, Settings: p.Settings, Tags: p.Tags, Address: p.Address}) {
+		t.Fatalf("unexpected profile: %+v", got)
+	}
+
+	// NULL扫描为零值，覆盖已有的值
+	if _, err := db.Exec("update profile set address = null where id=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(&got, "select * from profile where id=?", 2); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 2 || got.Settings != nil || got.Tags != nil || got.Address != (profileAddress{}) {
+		t.Fatalf("NULL should scan into zero values: %+v", got)
+	}
+
+	var profiles []profile
+	if err := db.QueryRows(&profiles, "select * from profile order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Settings["theme"] != "dark" || profiles[0].Address.City != "Shenzhen" || profiles[1].Tags != nil {
+		t.Fatalf("unexpected profiles: %+v", profiles)
+	}
+
+	if err := db.QueryRow(&got, "select id, 'not json' as settings, tags, address from profile where id=?", 1); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
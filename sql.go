@@ -3,23 +3,33 @@ package esql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 )
 
 type BaseSQL interface {
 	// Execute SQL (执行原生SQL)
 	Exec(query string, values ...interface{}) (sql.Result, error)
 	ExecContext(ctx context.Context, query string, values ...interface{}) (sql.Result, error)
-	// To query a single piece of data, the field order of v must be consistent with the column order.
-	// (查询单条数据，v的字段顺序必须与columns顺序一致)
+	// To query a single piece of data, columns are mapped to the fields of v by name.
+	// (查询单条数据，按列名映射到v的字段)
 	QueryRow(v interface{}, query string, values ...interface{}) error
 	QueryRowContext(ctx context.Context, v interface{}, query string, values ...interface{}) error
 
-	// To query multiple pieces of data, the field order of v must be consistent with the column order.
-	// (查询多条数据，v的字段顺序必须与columns顺序一致)
+	// To query multiple pieces of data, columns are mapped to the fields of v by name.
+	// (查询多条数据，按列名映射到v的字段)
 	QueryRows(v interface{}, query string, values ...interface{}) error
 	QueryRowsContext(ctx context.Context, v interface{}, query string, values ...interface{}) error
 }
 
+// ObjectSQL adds inserting, updating and deleting by the tags of struct v to BaseSQL,
+// both *DB and *Tx implement it. (在BaseSQL的基础上按结构体v的标签插入、更新和删除，*DB和*Tx都实现了该接口)
+type ObjectSQL interface {
+	BaseSQL
+	Insert(ctx context.Context, table string, v interface{}) (sql.Result, error)
+	Update(ctx context.Context, table string, v interface{}, where string, args ...interface{}) (sql.Result, error)
+	Delete(ctx context.Context, table string, v interface{}, where string, args ...interface{}) (sql.Result, error)
+}
+
 // Execute SQL (执行原生SQL)
 func (e *DB) Exec(query string, values ...interface{}) (sql.Result, error) {
 	return e.ExecContext(context.Background(), query, values...)
@@ -39,14 +49,14 @@ func (e *DB) ExecContext(ctx context.Context, query string, values ...interface{
 	return result, err
 }
 
-// To query a single piece of data, the field order of v must be consistent with the column order.
-// (查询单条数据，v的字段顺序必须与columns顺序一致)
+// To query a single piece of data, columns are mapped to the fields of v by name.
+// (查询单条数据，按列名映射到v的字段)
 func (e *DB) QueryRow(v interface{}, query string, values ...interface{}) error {
 	return e.QueryRowContext(context.Background(), v, query, values...)
 }
 
-// To query a single piece of data, the field order of v must be consistent with the column order.
-// (查询单条数据，v的字段顺序必须与columns顺序一致)
+// To query a single piece of data, columns are mapped to the fields of v by name.
+// (查询单条数据，按列名映射到v的字段)
 func (e *DB) QueryRowContext(ctx context.Context, v interface{}, query string, values ...interface{}) error {
 	query += " limit 1"
 	rows, err := e.db.QueryContext(ctx, query, values...)
@@ -56,19 +66,19 @@ func (e *DB) QueryRowContext(ctx context.Context, v interface{}, query string, v
 	}
 	defer rows.Close()
 
-	err = unmarshalRow(v, rows, true)
+	err = unmarshalRow(v, rows, !e.lenient)
 	e.logger.Output(query, err, values...)
 	return err
 }
 
-// To query multiple pieces of data, the field order of v must be consistent with the column order.
-// (查询多条数据，v的字段顺序必须与columns顺序一致)
+// To query multiple pieces of data, columns are mapped to the fields of v by name.
+// (查询多条数据，按列名映射到v的字段)
 func (e *DB) QueryRows(v interface{}, query string, values ...interface{}) error {
 	return e.QueryRowsContext(context.Background(), v, query, values...)
 }
 
-// To query multiple pieces of data, the field order of v must be consistent with the column order.
-// (查询多条数据，v的字段顺序必须与columns顺序一致)
+// To query multiple pieces of data, columns are mapped to the fields of v by name.
+// (查询多条数据，按列名映射到v的字段)
 func (e *DB) QueryRowsContext(ctx context.Context, v interface{}, query string, values ...interface{}) error {
 	rows, err := e.db.QueryContext(ctx, query, values...)
 	if err != nil {
@@ -77,11 +87,47 @@ func (e *DB) QueryRowsContext(ctx context.Context, v interface{}, query string,
 	}
 	defer rows.Close()
 
-	err = unmarshalRows(v, rows, true)
+	err = unmarshalRows(v, rows, !e.lenient)
 	e.logger.Output(query, err, values...)
 	return err
 }
 
+// Insert v into the table, columns are derived from the fields of v, a zero autoincr field is set to the
+// generated ID from LastInsertId, or RETURNING on PostgreSQL.
+// (插入v，按v的字段生成列，自增字段为零值时写回生成的ID，PostgreSQL使用RETURNING获取)
+/*
+	user := User{Name: "tom"}
+	_, err := db.Insert(ctx, "user", &user)
+	fmt.Println(user.ID)
+*/
+func (e *DB) Insert(ctx context.Context, table string, v interface{}) (sql.Result, error) {
+	return insert(ctx, e.db, e.logger, e.dialect, table, v)
+}
+
+// Update the table with the writable fields of v, the pk fields of v are used when where is empty.
+// args of where are bound after the values of the fields on every dialect, on PostgreSQL where is still
+// numbered from $1 and the placeholders are shifted past the fields.
+// (用v的可写字段更新表，where为空时按v的主键更新；where的参数排在字段值之后，PostgreSQL的where仍从$1开始编号，会自动后移)
+/*
+	_, err := db.Update(ctx, "user", &user, "")
+	_, err = db.Update(ctx, "user", &user, "name = ?", "tom")
+	// PostgreSQL: update "user" set "name"=$1 where name = $2
+	_, err = db.Update(ctx, "user", &user, "name = $1", "tom")
+*/
+func (e *DB) Update(ctx context.Context, table string, v interface{}, where string, args ...interface{}) (sql.Result, error) {
+	return update(ctx, e.db, e.logger, e.dialect, table, v, where, args...)
+}
+
+// Delete from the table, the pk fields of v are used when where is empty, v can be nil with a where condition.
+// (删除记录，where为空时按v的主键删除，有where条件时v可以为nil)
+/*
+	_, err := db.Delete(ctx, "user", &user, "")
+	_, err = db.Delete(ctx, "user", nil, "name = ?", "tom")
+*/
+func (e *DB) Delete(ctx context.Context, table string, v interface{}, where string, args ...interface{}) (sql.Result, error) {
+	return del(ctx, e.db, e.logger, e.dialect, table, v, where, args...)
+}
+
 // Open transaction (开启事务)
 func (e *DB) Begin() (*Tx, error) {
 	tx, err := e.db.Begin()
@@ -89,7 +135,7 @@ func (e *DB) Begin() (*Tx, error) {
 		return nil, err
 	}
 
-	return &Tx{tx: tx, logger: e.logger}, nil
+	return &Tx{tx: tx, logger: e.logger, lenient: e.lenient, dialect: e.dialect}, nil
 }
 
 // Automate transactions (自动化事务)
@@ -118,17 +164,22 @@ func (e *DB) Transaction(fn func(tx *Tx) error) error {
 }
 
 // Generate struct by table (通过表结构生成结构体)
-func (e *DB) GenStructByTable(mode, dbName, savePath string, hasTag bool) error {
+func (e *DB) GenStructByTable(mode, dbName, savePath string, hasTag bool, opts ...GenOption) error {
 	var err error
+	opt := newGenOptions(opts...)
+	if err = opt.loadTemplates(); err != nil {
+		return err
+	}
+
 	switch mode {
 	case Mysql:
-		err = genStructByMysqlTable(e, dbName, savePath, hasTag)
+		err = genStructByMysqlTable(e, dbName, savePath, hasTag, opt)
 	case Postgres:
-		err = genStructByPostgresSqlTable(e, dbName, savePath, hasTag)
+		err = genStructByPostgresSqlTable(e, dbName, savePath, hasTag, opt)
 	case SQLite:
-		err = genStructBySQLiteTable(e, dbName, savePath, hasTag)
+		err = genStructBySQLiteTable(e, dbName, savePath, hasTag, opt)
 	default:
-
+		err = fmt.Errorf("unsupported mode %q", mode)
 	}
 
 	return err
@@ -2,89 +2,86 @@ package esql
 
 import (
 	"fmt"
+	"go/token"
 	"reflect"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // 获取结构体中的字段，只接受结构体/指针
 func RawFieldNames(in interface{}, postgreSql ...bool) []string {
-	out := make([]string, 0)
-	v := reflect.ValueOf(in)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+	meta := rawStructMeta(in)
+	fields := make([]*FieldMeta, 0, len(meta.Fields))
+	for _, field := range meta.Fields {
+		if !field.Alias {
+			fields = append(fields, field)
+		}
+	}
+
+	return quoteFieldColumns(fields, postgreSql...)
+}
+
+// 获取结构体中更新时写入的字段，与Meta.UpdateFields一致，不包括readonly、主键和自增字段
+/*
+	fieldsWithPlaceHolder := esql.RawUpdateFieldsWithPlaceHolder(esql.RawUpdateFieldNames(&user))
+*/
+func RawUpdateFieldNames(in interface{}, postgreSql ...bool) []string {
+	return quoteFieldColumns(rawStructMeta(in).UpdateFields(), postgreSql...)
+}
+
+func rawStructMeta(in interface{}) *Meta {
+	t := reflect.TypeOf(in)
+	if t == nil || Deref(t).Kind() != reflect.Struct {
+		panic(fmt.Errorf("only accepts structs; got %T", in))
 	}
 
+	return getStructMeta(Deref(t))
+}
+
+// 字段的列名，PostgreSQL不加反引号
+func quoteFieldColumns(fields []*FieldMeta, postgreSql ...bool) []string {
 	var pg bool
 	if len(postgreSql) > 0 {
 		pg = postgreSql[0]
 	}
 
+	out := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if pg {
+			out = append(out, field.Column)
+		} else {
+			out = append(out, fmt.Sprintf("`%s`", field.Column))
+		}
+	}
+
+	return out
+}
+
+// 获取结构体的字段值，顺序与RawFieldNames一致，excludes为需要排除的字段，json选项的字段会序列化为字符串
+/*
+	fieldNames := esql.RemoveFieldName(esql.RawFieldNames(&user), "`id`")
+	values := esql.RawFieldValues(&user, "`id`")
+*/
+func RawFieldValues(in interface{}, excludes ...string) []interface{} {
+	v := reflect.Indirect(reflect.ValueOf(in))
 	if v.Kind() != reflect.Struct {
-		panic(fmt.Errorf("only accepts structs; got %T", v))
+		panic(fmt.Errorf("only accepts structs; got %T", in))
 	}
 
-	typ := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		// gets a StructField
-		fi := typ.Field(i)
-		vi := v.Field(i)
-		if fi.Type.Kind() == reflect.Ptr {
-			vi = vi.Elem()
-		}
-		if vi.Kind() == reflect.Struct {
-			for j := 0; j < vi.NumField(); j++ {
-				typJ := vi.Type()
-				fj := typJ.Field(j)
-				tagv := fj.Tag.Get(tagName)
-				switch tagv {
-				case "-":
-					continue
-				case "":
-					// 默认驼峰字段名转为下划线格式
-					tagv = ConvertCamelToSnake(fi.Name)
-					if pg {
-						out = append(out, tagv)
-					} else {
-						out = append(out, fmt.Sprintf("`%s`", tagv))
-					}
-				default:
-					if len(tagv) == 0 {
-						tagv = ConvertCamelToSnake(fi.Name)
-					}
-					if pg {
-						out = append(out, tagv)
-					} else {
-						out = append(out, fmt.Sprintf("`%s`", tagv))
-					}
-				}
-			}
+	excluded := make(map[string]bool, len(excludes))
+	for _, exclude := range excludes {
+		excluded[strings.Trim(exclude, "`\"")] = true
+	}
 
+	meta := getStructMeta(v.Type())
+	out := make([]interface{}, 0, len(meta.Fields))
+	for _, field := range meta.Fields {
+		if field.Alias || excluded[field.Column] {
 			continue
 		}
 
-		tagv := fi.Tag.Get(tagName)
-		switch tagv {
-		case "-":
-			continue
-		case "":
-			// 默认驼峰字段名转为下划线格式
-			tagv = ConvertCamelToSnake(fi.Name)
-			if pg {
-				out = append(out, tagv)
-			} else {
-				out = append(out, fmt.Sprintf("`%s`", tagv))
-			}
-		default:
-			if len(tagv) == 0 {
-				tagv = ConvertCamelToSnake(fi.Name)
-			}
-			if pg {
-				out = append(out, tagv)
-			} else {
-				out = append(out, fmt.Sprintf("`%s`", tagv))
-			}
-		}
+		out = append(out, field.ValueOf(v))
 	}
 
 	return out
@@ -118,52 +115,215 @@ func AddFieldPrefix(strs []string, prefix string) []string {
 	return out
 }
 
-// 驼峰转下划线格式
-func ConvertCamelToSnake(s string) string {
-	var result string
-	var words []string
-	lastIndex := 0
-	rs := []rune(s)
-
-	for i := 0; i < len(rs); i++ {
-		if i > 0 && unicode.IsUpper(rs[i]) {
-			words = append(words, string(rs[lastIndex:i]))
-			lastIndex = i
+// DefaultInitialisms are the initialisms kept in upper case by default, e.g. user_id -> UserID.
+// (默认保持大写的缩略词)
+var DefaultInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP", "HTTPS", "ID", "IP", "JSON",
+	"LHS", "QPS", "RAM", "RHS", "RPC", "SLA", "SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID",
+	"UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// 缩略词集合，键为大写形式
+type initialismSet map[string]bool
+
+func newInitialismSet(words []string) initialismSet {
+	set := make(initialismSet, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			set[strings.ToUpper(word)] = true
 		}
 	}
 
-	words = append(words, string(rs[lastIndex:]))
+	return set
+}
+
+// 运行时列名映射使用的缩略词，固定为DefaultInitialisms，不受生成选项影响
+var defaultInitialisms = newInitialismSet(DefaultInitialisms)
+
+func (set initialismSet) has(word string) bool {
+	return set[strings.ToUpper(word)]
+}
+
+// 缩略词的复数形式，如IDs
+func (set initialismSet) hasPlural(word string) bool {
+	return len(word) > 2 && (word[len(word)-1] == 's' || word[len(word)-1] == 'S') && set.has(word[:len(word)-1])
+}
 
-	for k, word := range words {
-		if k > 0 {
-			result += "_"
+// 拆分为单词，非字母数字的字符作为分隔符，驼峰格式按大小写拆分，如HTTPServer拆分为HTTP和Server
+func (set initialismSet) splitWords(s string) []string {
+	words := make([]string, 0, 4)
+	for _, segment := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		rs := []rune(segment)
+		start := 0
+		for i := 1; i < len(rs); i++ {
+			prev, cur := rs[i-1], rs[i]
+			switch {
+			case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+				// userId、sha256Sum
+			case unicode.IsUpper(cur) && unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1]):
+				// HTTPServer在S前拆分，IDs不拆分
+				if rs[i+1] == 's' && (i+2 == len(rs) || !unicode.IsLower(rs[i+2])) && set.has(string(rs[start:i+1])) {
+					continue
+				}
+			default:
+				continue
+			}
+
+			words = set.appendWord(words, string(rs[start:i]))
+			start = i
 		}
+		words = set.appendWord(words, string(rs[start:]))
+	}
 
-		result += strings.ToLower(word)
+	return words
+}
+
+// 添加单词，连续的缩略词拆开，如APIURL拆分为API和URL
+func (set initialismSet) appendWord(words []string, word string) []string {
+	if len(word) > 2 && strings.ToUpper(word) == word && !set.has(word) {
+		if parts := set.split(word); parts != nil {
+			return append(words, parts...)
+		}
 	}
 
-	return result
+	return append(words, word)
 }
 
-// 大写驼峰格式
-func ConvertToCamel(s string) string {
-	temp := strings.Split(s, "_") // 有下划线的，需要拆分
-	var str string
-	for i := 0; i < len(temp); i++ {
-		b := []rune(temp[i])
-		for j := 0; j < len(b); j++ {
-			if j == 0 {
-				// 首字母大写转换
-				b[j] -= 32
-				str += string(b[j])
-			} else {
-				str += string(b[j])
-			}
+// 按最长匹配拆分为缩略词，不能完全拆分时返回nil
+func (set initialismSet) split(word string) []string {
+	if word == "" {
+		return []string{}
+	}
+
+	for i := len(word); i > 1; i-- {
+		if !set.has(word[:i]) {
+			continue
+		}
+		if rest := set.split(word[i:]); rest != nil {
+			return append([]string{word[:i]}, rest...)
+		}
+	}
+
+	return nil
+}
+
+// 单词转为首字母大写，缩略词全部大写
+func (set initialismSet) titleWord(word string, initialism bool) string {
+	if initialism {
+		if set.has(word) {
+			return strings.ToUpper(word)
+		}
+		if set.hasPlural(word) {
+			return strings.ToUpper(word[:len(word)-1]) + "s"
 		}
 	}
+
+	rs := []rune(strings.ToLower(word))
+	rs[0] = unicode.ToUpper(rs[0])
+	return string(rs)
+}
+
+// 驼峰转下划线格式
+func (set initialismSet) snake(s string) string {
+	words := set.splitWords(s)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+
+	return strings.Join(words, "_")
+}
+
+// 大写驼峰格式的导出标识符
+func (set initialismSet) camel(s string) string {
+	var b strings.Builder
+	for _, word := range set.splitWords(s) {
+		b.WriteString(set.titleWord(word, true))
+	}
+
+	str := b.String()
+	// 以数字或非大写字母（如中文）开头时不是导出的标识符
+	if r, _ := utf8.DecodeRuneInString(str); str != "" && !unicode.IsUpper(r) {
+		str = "X" + str
+	}
+
 	return str
 }
 
+// 小写驼峰格式，用于标签，不处理缩略词
+func (set initialismSet) tagCamel(s string) string {
+	words := set.splitWords(s)
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+		} else {
+			words[i] = set.titleWord(word, false)
+		}
+	}
+
+	return strings.Join(words, "")
+}
+
+// 小写驼峰格式的标识符，如user_id -> userID，id -> id，关键字加上下划线后缀
+func (set initialismSet) lowerCamel(s string) string {
+	words := set.splitWords(s)
+	if len(words) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.ToLower(words[0]))
+	for _, word := range words[1:] {
+		b.WriteString(set.titleWord(word, true))
+	}
+
+	str := b.String()
+	if r, _ := utf8.DecodeRuneInString(str); unicode.IsDigit(r) {
+		str = "x" + str
+	}
+	if token.IsKeyword(str) {
+		str += "_"
+	}
+
+	return str
+}
+
+// ConvertCamelToSnake converts a camel case name to snake case with DefaultInitialisms,
+// e.g. UserID -> user_id, HTTPServer -> http_server.
+// (驼峰转下划线格式，使用默认缩略词，与结构体字段到列名的映射一致)
+func ConvertCamelToSnake(s string) string {
+	return defaultInitialisms.snake(s)
+}
+
+// ConvertToCamel converts a name to an exported Go identifier with DefaultInitialisms,
+// e.g. user_id -> UserID, 2fa_code -> X2faCode.
+// (转为大写驼峰格式的导出标识符，使用默认缩略词)
+func ConvertToCamel(s string) string {
+	return defaultInitialisms.camel(s)
+}
+
+// 小写驼峰格式的标识符，使用默认缩略词
+func convertToLowerCamel(s string) string {
+	return defaultInitialisms.lowerCamel(s)
+}
+
+// 英文单词的复数形式
+func pluralize(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case lower == "":
+		return s
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return s[:len(s)-1] + "ies"
+	default:
+		return s + "s"
+	}
+}
+
 // 获取查询字段
 func RawQueryFields(fieldNames []string) string {
 	return strings.Join(fieldNames, ",")
@@ -174,7 +334,7 @@ func RawQueryFieldsWithPrefix(fieldNames []string, table string) string {
 	return strings.Join(AddFieldPrefix(fieldNames, table), ",")
 }
 
-// 获取带占位符的更新字段
+// 获取带占位符的更新字段，str为需要排除的字段，fieldNames通过RawUpdateFieldNames获取时不包括readonly和自增字段
 func RawUpdateFieldsWithPlaceHolder(fieldNames []string, str ...string) string {
 	return strings.Join(RemoveFieldName(fieldNames, str...), "=?,") + "=?"
 }
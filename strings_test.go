@@ -0,0 +1,139 @@
+package esql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertToCamel(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"user_id", "UserID"},
+		{"id", "ID"},
+		{"user_ids", "UserIDs"},
+		{"userIDs", "UserIDs"},
+		{"api_url", "APIURL"},
+		{"APIURL", "APIURL"},
+		{"http_server", "HTTPServer"},
+		{"HTTPServer", "HTTPServer"},
+		{"2fa_code", "X2faCode"},
+		{"user-name", "UserName"},
+		{"sha256_sum", "Sha256Sum"},
+		{"UUID", "UUID"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ConvertToCamel(tt.in); got != tt.want {
+			t.Errorf("ConvertToCamel(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConvertCamelToSnake(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"UserID", "user_id"},
+		{"ID", "id"},
+		{"HTTPServer", "http_server"},
+		{"UserIDs", "user_ids"},
+		{"IDs", "ids"},
+		{"APIURL", "api_url"},
+		{"userId", "user_id"},
+		{"Sha256Sum", "sha256_sum"},
+		{"X2faCode", "x2fa_code"},
+		{"SKUID", "skuid"},
+	}
+	for _, tt := range tests {
+		if got := ConvertCamelToSnake(tt.in); got != tt.want {
+			t.Errorf("ConvertCamelToSnake(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	for _, column := range []string{"user_id", "api_url", "http_server", "user_ids", "created_at", "uuid"} {
+		if got := ConvertCamelToSnake(ConvertToCamel(column)); got != column {
+			t.Errorf("%s -> %s -> %s", column, ConvertToCamel(column), got)
+		}
+	}
+	// 不能转回原列名的需要生成标签
+	if got := ConvertCamelToSnake(ConvertToCamel("2fa_code")); got == "2fa_code" {
+		t.Errorf("2fa_code should not round trip, got %s", got)
+	}
+}
+
+func TestConvertToLowerCamel(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"user_id", "userID"},
+		{"id", "id"},
+		{"ID", "id"},
+		{"api_url", "apiURL"},
+		{"2fa_code", "x2faCode"},
+		{"type", "type_"},
+		{"func", "func_"},
+		{"default", "default_"},
+		{"range_id", "rangeID"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := convertToLowerCamel(tt.in); got != tt.want {
+			t.Errorf("convertToLowerCamel(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWithInitialisms(t *testing.T) {
+	opt := newGenOptions(WithInitialisms("sku", " vip "))
+	tests := []struct {
+		in, want string
+	}{
+		{"sku_id", "SKUID"},
+		{"vip_level", "VIPLevel"},
+		{"user_id", "UserID"},
+	}
+	for _, tt := range tests {
+		if got := opt.initialisms.camel(tt.in); got != tt.want {
+			t.Errorf("camel(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	// 不影响运行时的映射
+	if got := ConvertToCamel("sku_id"); got != "SkuID" {
+		t.Errorf("ConvertToCamel(sku_id) = %s, want SkuID", got)
+	}
+	type product struct {
+		SkuID int64
+		SKUID int64
+	}
+	var columns []string
+	for _, field := range getStructMeta(reflect.TypeOf(product{})).Fields {
+		columns = append(columns, field.Column)
+	}
+	if !reflect.DeepEqual(columns, []string{"sku_id", "skuid"}) {
+		t.Errorf("unexpected columns: %v", columns)
+	}
+}
+
+func TestRawUpdateFieldNames(t *testing.T) {
+	type user struct {
+		ID        int64  `esql:"id,pk,autoincr"`
+		Seq       int64  `esql:"seq,autoincr"`
+		Name      string `esql:"name"`
+		Email     string
+		CreatedAt string `esql:"created_at,readonly"`
+	}
+
+	if got := RawUpdateFieldNames(&user{}); !reflect.DeepEqual(got, []string{"`name`", "`email`"}) {
+		t.Errorf("unexpected fields: %v", got)
+	}
+	if got := RawUpdateFieldNames(user{}, true); !reflect.DeepEqual(got, []string{"name", "email"}) {
+		t.Errorf("unexpected postgres fields: %v", got)
+	}
+	if got := RawUpdateFieldsWithPlaceHolder(RawUpdateFieldNames(&user{}), "`email`"); got != "`name`=?" {
+		t.Errorf("unexpected placeholders: %s", got)
+	}
+}
@@ -0,0 +1,350 @@
+package esql
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed struct.tpl
+var structTemplate string
+
+//go:embed crud.tpl
+var crudTemplate string
+
+// 模板函数
+var templateFuncs = template.FuncMap{
+	"snake":      ConvertCamelToSnake,
+	"camel":      ConvertToCamel,
+	"lowerCamel": convertToLowerCamel,
+	"plural":     pluralize,
+	"comment":    formatComment,
+	// 增删改查模板使用
+	"quote":        quoteIdent,
+	"columns":      joinColumns,
+	"placeholders": joinPlaceholders,
+	"assignments":  joinAssignments,
+	"conditions":   joinConditions,
+	"insertable":   insertableFields,
+	"updatable":    updatableFields,
+	"params":       joinParams,
+	"args":         joinArgs,
+	"add": func(a, b int) int {
+		return a + b
+	},
+}
+
+// 多行注释的每一行都加上注释符
+func formatComment(s string) string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n")), "\n")
+	return strings.Join(lines, "\n// ")
+}
+
+// 按数据库类型引用标识符
+func quoteIdent(mode, name string) string {
+	if mode == Postgres {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// 第n个占位符，PostgreSQL使用$n
+func placeholder(mode string, n int) string {
+	if mode == Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+
+	return "?"
+}
+
+func joinColumns(mode string, fields []Field) string {
+	out := make([]string, 0, len(fields))
+	for _, field := range fields {
+		out = append(out, quoteIdent(mode, field.Name))
+	}
+
+	return strings.Join(out, ",")
+}
+
+func joinPlaceholders(mode string, start int, fields []Field) string {
+	out := make([]string, 0, len(fields))
+	for i := range fields {
+		out = append(out, placeholder(mode, start+i))
+	}
+
+	return strings.Join(out, ",")
+}
+
+func joinAssignments(mode string, start int, fields []Field) string {
+	out := make([]string, 0, len(fields))
+	for i, field := range fields {
+		out = append(out, quoteIdent(mode, field.Name)+"="+placeholder(mode, start+i))
+	}
+
+	return strings.Join(out, ",")
+}
+
+func joinConditions(mode string, start int, fields []Field) string {
+	out := make([]string, 0, len(fields))
+	for i, field := range fields {
+		out = append(out, quoteIdent(mode, field.Name)+"="+placeholder(mode, start+i))
+	}
+
+	return strings.Join(out, " and ")
+}
+
+// 插入的字段，不包括自增字段
+func insertableFields(fields []Field) []Field {
+	out := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if !field.AutoIncrement {
+			out = append(out, field)
+		}
+	}
+
+	return out
+}
+
+// 更新的字段，不包括主键和自增字段
+func updatableFields(fields []Field) []Field {
+	out := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if !field.PrimaryKey && !field.AutoIncrement {
+			out = append(out, field)
+		}
+	}
+
+	return out
+}
+
+// 方法参数名，避免与方法内的变量冲突，关键字已由convertToLowerCamel处理
+func paramName(field Field) string {
+	name := convertToLowerCamel(field.Name)
+	switch name {
+	case "ctx", "m", "query", "resp", "err":
+		return name + "Value"
+	default:
+		return name
+	}
+}
+
+func joinParams(fields []Field) string {
+	out := make([]string, 0, len(fields))
+	for _, field := range fields {
+		out = append(out, paramName(field)+" "+field.Type)
+	}
+
+	return strings.Join(out, ", ")
+}
+
+// prefix为空时使用参数名，否则使用prefix加字段名
+func joinArgs(prefix string, fields []Field) string {
+	out := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if prefix == "" {
+			out = append(out, paramName(field))
+		} else {
+			out = append(out, prefix+field.CamelName)
+		}
+	}
+
+	return strings.Join(out, ", ")
+}
+
+// 模板及其生成文件的后缀
+type genTemplate struct {
+	suffix string
+	tmpl   *template.Template
+}
+
+// 模板函数，命名转换使用选项中的缩略词
+func (o *genOptions) templateFuncs() template.FuncMap {
+	funcs := make(template.FuncMap, len(templateFuncs))
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	funcs["snake"] = o.initialisms.snake
+	funcs["camel"] = o.initialisms.camel
+	funcs["lowerCamel"] = o.initialisms.lowerCamel
+
+	return funcs
+}
+
+// 加载模板，没有自定义模板则使用内置模板
+func (o *genOptions) loadTemplates() error {
+	if err := o.loadStructTemplates(); err != nil {
+		return err
+	}
+
+	if o.crud {
+		tmpl, err := template.New("crudTemplate").Funcs(o.templateFuncs()).Parse(crudTemplate)
+		if err != nil {
+			return err
+		}
+
+		o.templates = append(o.templates, genTemplate{suffix: "_model", tmpl: tmpl})
+	}
+
+	return nil
+}
+
+func (o *genOptions) loadStructTemplates() error {
+	if o.template == "" {
+		tmpl, err := template.New("structTemplate").Funcs(o.templateFuncs()).Parse(structTemplate)
+		if err != nil {
+			return err
+		}
+
+		o.templates = []genTemplate{{tmpl: tmpl}}
+		return nil
+	}
+
+	fi, err := os.Stat(o.template)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		tmpl, err := parseTemplateFile(o.template, o.templateFuncs())
+		if err != nil {
+			return err
+		}
+
+		o.templates = []genTemplate{{tmpl: tmpl}}
+		return nil
+	}
+
+	// 文件名已排序
+	files, err := filepath.Glob(filepath.Join(o.template, "*.tpl"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no *.tpl files in template directory %s", o.template)
+	}
+
+	o.templates = make([]genTemplate, 0, len(files))
+	for _, file := range files {
+		tmpl, err := parseTemplateFile(file, o.templateFuncs())
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(filepath.Base(file), ".tpl")
+		o.templates = append(o.templates, genTemplate{suffix: "_" + name, tmpl: tmpl})
+	}
+
+	return nil
+}
+
+func parseTemplateFile(filename string, funcs template.FuncMap) (*template.Template, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.New(filepath.Base(filename)).Funcs(funcs).Parse(string(data))
+}
+
+// 执行模板，格式化后写入文件
+func executeTemplate(tmpl *template.Template, structInfo StructInfo, filename string) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, structInfo); err != nil {
+		return fmt.Errorf("execute template %s for table %s: %w", tmpl.Name(), structInfo.Table, err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format generated code of table %s: %w", structInfo.Table, err)
+	}
+
+	// 自定义模板没有头部注释时补上，再次生成时才能覆盖
+	if !isGenerated(src) {
+		src = append([]byte(generatedHeader+"\n\n"), src...)
+	}
+
+	return writeFileAtomic(filename, src)
+}
+
+// 生成文件的头部注释
+const generatedHeader = "// Code generated by esql. DO NOT EDIT."
+
+// https://golang.org/s/generatedcode
+var generatedPattern = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+// 是否为生成的代码，头部注释需在package之前
+func isGenerated(src []byte) bool {
+	if i := bytes.Index(src, []byte("\npackage ")); i >= 0 {
+		src = src[:i]
+	} else if !bytes.HasPrefix(src, []byte("package ")) {
+		return false
+	} else {
+		src = nil
+	}
+
+	return generatedPattern.Match(src)
+}
+
+// 旧版本esql生成的文件没有头部注释，按旧的内置模板完整匹配：导入、字段变量、只有字段的结构体和TableName方法，
+// 手动添加了导入、字段选项或方法的文件不匹配
+var legacyGeneratedPattern = regexp.MustCompile(`^\s*package \w+\s+` +
+	`(?:import \(\s*(?:"(?:github\.com/cyj19/esql|time)"\s*)*\)\s*)?` +
+	`var \(\s*\w+FieldNames\s*=\s*esql\.RawFieldNames\(&\w+\{\}\)\s*` +
+	`// 查询字段\s*\w+Fields\s*=\s*esql\.RawQueryFields\(\w+FieldNames\)\s*` +
+	`// 更新字段\s*\w+FieldsWithPlaceHolder\s*=\s*esql\.RawUpdateFieldsWithPlaceHolder\(\w+FieldNames, ` + "`id`" + `\)\s*\)\s*` +
+	`type \w+ struct \{\s*(?:\w+[ \t]+[\w.]+(?:[ \t]+` + "`" + `esql:"\w*"` + "`" + `)?[ \t]*\n\s*)*\}\s*` +
+	`func \(\w+\) TableName\(\) string \{\s*return "[^"\n]*"\s*\}\s*\z`)
+
+// 是否为旧版本esql生成的代码
+func isLegacyGenerated(src []byte) bool {
+	return legacyGeneratedPattern.Match(src)
+}
+
+// 已存在且不是生成的文件，可能被手动修改过，不能覆盖
+func checkGenerated(filename string) error {
+	src, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !isGenerated(src) && !isLegacyGenerated(src) {
+		return fmt.Errorf("%s exists and is neither generated by esql nor has a \"// Code generated ... DO NOT EDIT.\" header, "+
+			"it may have been edited by hand, use WithForce(true) or -force to overwrite it", filename)
+	}
+
+	return nil
+}
+
+// 先写入临时文件再重命名，避免生成一半的文件
+func writeFileAtomic(filename string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,75 @@
+package esql
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 旧版本esql生成的文件，没有头部注释也没有格式化
+const legacyGenerated = `package model
+
+import (
+    "github.com/cyj19/esql"
+    
+)
+
+
+var (
+    UserFieldNames = esql.RawFieldNames(&User{})
+    // 查询字段
+    UserFields = esql.RawQueryFields(UserFieldNames)
+    // 更新字段
+    UserFieldsWithPlaceHolder = esql.RawUpdateFieldsWithPlaceHolder(UserFieldNames, ` + "`id`" + `)
+)
+
+type User struct {
+ Id int64  ` + "`esql:\"id\"`" + ` 
+ Name string  ` + "`esql:\"name\"`" + ` 
+
+}
+
+func (User) TableName() string {
+	return "user"
+}
+`
+
+func TestCheckGenerated(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{"generated.go", generatedHeader + "\n\npackage model\n", true},
+		{"custom_header.go", "// Code generated by sqlc. DO NOT EDIT.\npackage model\n", true},
+		{"legacy.go", legacyGenerated, true},
+		{"legacy_gofmt.go", strings.NewReplacer("    ", "\t", " \n", "\n", "  `", " `").Replace(legacyGenerated), true},
+		{"legacy_edited.go", strings.Replace(legacyGenerated, "func (User) TableName", "func (u User) TableName", 1), false},
+		{"legacy_method.go", legacyGenerated + "\nfunc (u User) Greeting() string {\n\treturn \"hi \" + u.Name\n}\n", false},
+		{"legacy_field.go", strings.Replace(legacyGenerated, " \n\n}", " \n Note string `json:\"note\"`\n}", 1), false},
+		{"legacy_import.go", strings.Replace(legacyGenerated, "\"github.com/cyj19/esql\"", "\"fmt\"\n    \"github.com/cyj19/esql\"", 1), false},
+		{"legacy_code_before.go", strings.Replace(legacyGenerated, "type User struct", "const role = \"admin\"\n\ntype User struct", 1), false},
+		{"handwritten.go", "package model\n\ntype User struct{}\n\nfunc (User) TableName() string {\n\treturn \"user\"\n}\n", false},
+		{"header_after_package.go", "package model\n\n" + generatedHeader + "\n", false},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := checkGenerated(filename)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && (err == nil || !strings.Contains(err.Error(), "-force")) {
+			t.Errorf("%s: expected an error suggesting -force, got %v", tt.name, err)
+		}
+	}
+
+	if err := checkGenerated(filepath.Join(dir, "missing.go")); err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+}
@@ -6,8 +6,18 @@ import (
 )
 
 type Tx struct {
-	tx     *sql.Tx
-	logger Logger
+	tx      *sql.Tx
+	logger  Logger
+	lenient bool
+	dialect string
+}
+
+// WithStrict returns a Tx sharing the same transaction with the strict mode changed, see DB.SetStrict.
+// (返回共享事务、只修改严格模式的Tx)
+func (e *Tx) WithStrict(strict bool) *Tx {
+	tx := *e
+	tx.lenient = !strict
+	return &tx
 }
 
 // Execute SQL (执行原生SQL)
@@ -29,14 +39,14 @@ func (e *Tx) ExecContext(ctx context.Context, query string, values ...interface{
 	return result, err
 }
 
-// To query a single piece of data, the field order of v must be consistent with the column order.
-// (查询单条数据，v的字段顺序必须与columns顺序一致)
+// To query a single piece of data, columns are mapped to the fields of v by name.
+// (查询单条数据，按列名映射到v的字段)
 func (e *Tx) QueryRow(v interface{}, query string, values ...interface{}) error {
 	return e.QueryRowContext(context.Background(), v, query, values...)
 }
 
-// To query a single piece of data, the field order of v must be consistent with the column order.
-// (查询单条数据，v的字段顺序必须与columns顺序一致)
+// To query a single piece of data, columns are mapped to the fields of v by name.
+// (查询单条数据，按列名映射到v的字段)
 func (e *Tx) QueryRowContext(ctx context.Context, v interface{}, query string, values ...interface{}) error {
 	// 追加limit 1
 	query += " limit 1"
@@ -47,19 +57,19 @@ func (e *Tx) QueryRowContext(ctx context.Context, v interface{}, query string, v
 	}
 	defer rows.Close()
 
-	err = unmarshalRow(v, rows, true)
+	err = unmarshalRow(v, rows, !e.lenient)
 	e.logger.Output(query, err, values...)
 	return err
 }
 
-// To query multiple pieces of data, the field order of v must be consistent with the column order.
-// (查询多条数据，v的字段顺序必须与columns顺序一致)
+// To query multiple pieces of data, columns are mapped to the fields of v by name.
+// (查询多条数据，按列名映射到v的字段)
 func (e *Tx) QueryRows(v interface{}, query string, values ...interface{}) error {
 	return e.QueryRowsContext(context.Background(), v, query, values...)
 }
 
-// To query multiple pieces of data, the field order of v must be consistent with the column order.
-// (查询多条数据，v的字段顺序必须与columns顺序一致)
+// To query multiple pieces of data, columns are mapped to the fields of v by name.
+// (查询多条数据，按列名映射到v的字段)
 func (e *Tx) QueryRowsContext(ctx context.Context, v interface{}, query string, values ...interface{}) error {
 	rows, err := e.tx.QueryContext(ctx, query, values...)
 	if err != nil {
@@ -68,11 +78,29 @@ func (e *Tx) QueryRowsContext(ctx context.Context, v interface{}, query string,
 	}
 	defer rows.Close()
 
-	err = unmarshalRows(v, rows, true)
+	err = unmarshalRows(v, rows, !e.lenient)
 	e.logger.Output(query, err, values...)
 	return err
 }
 
+// Insert v into the table, columns are derived from the fields of v, a zero autoincr field is set to the generated ID.
+// (插入v，按v的字段生成列，自增字段为零值时写回生成的ID)
+func (e *Tx) Insert(ctx context.Context, table string, v interface{}) (sql.Result, error) {
+	return insert(ctx, e.tx, e.logger, e.dialect, table, v)
+}
+
+// Update the table with the writable fields of v, the pk fields of v are used when where is empty, see DB.Update.
+// (用v的可写字段更新表，where为空时按v的主键更新)
+func (e *Tx) Update(ctx context.Context, table string, v interface{}, where string, args ...interface{}) (sql.Result, error) {
+	return update(ctx, e.tx, e.logger, e.dialect, table, v, where, args...)
+}
+
+// Delete from the table, the pk fields of v are used when where is empty.
+// (删除记录，where为空时按v的主键删除)
+func (e *Tx) Delete(ctx context.Context, table string, v interface{}, where string, args ...interface{}) (sql.Result, error) {
+	return del(ctx, e.tx, e.logger, e.dialect, table, v, where, args...)
+}
+
 // Commit transaction (提交事务)
 func (e *Tx) Commit() error {
 	return e.tx.Commit()
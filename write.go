@@ -0,0 +1,276 @@
+package esql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// *sql.DB和*sql.Tx的公共方法
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// PostgreSQL通过RETURNING获取自增ID时的结果
+type returningResult struct {
+	id int64
+}
+
+func (r returningResult) LastInsertId() (int64, error) {
+	return r.id, nil
+}
+
+func (r returningResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+// 引用表名，支持schema.table
+func quoteTable(dialect, table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdent(dialect, part)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// 获取结构体指针的值和映射信息
+func writeTarget(v interface{}) (reflect.Value, *Meta, error) {
+	rv := reflect.ValueOf(v)
+	if err := ValidatePtr(rv); err != nil {
+		return reflect.Value{}, nil, err
+	}
+
+	rve := reflect.Indirect(rv)
+	if rve.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, ErrUnsupportedValueType
+	}
+
+	return rve, getStructMeta(rve.Type()), nil
+}
+
+// 插入v，自增字段为零值时写回自增ID
+func insert(ctx context.Context, conn sqlConn, logger Logger, dialect, table string, v interface{}) (sql.Result, error) {
+	rv, meta, err := writeTarget(v)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := meta.InsertFields(rv)
+	columns := make([]string, 0, len(fields))
+	placeholders := make([]string, 0, len(fields))
+	values := make([]interface{}, 0, len(fields))
+	for i, f := range fields {
+		columns = append(columns, quoteIdent(dialect, f.Column))
+		placeholders = append(placeholders, placeholder(dialect, i+1))
+		values = append(values, f.ValueOf(rv))
+	}
+
+	var query string
+	switch {
+	case len(fields) > 0:
+		query = fmt.Sprintf("insert into %s (%s) values (%s)", quoteTable(dialect, table), strings.Join(columns, ","), strings.Join(placeholders, ","))
+	case dialect == Mysql:
+		query = fmt.Sprintf("insert into %s () values ()", quoteTable(dialect, table))
+	default:
+		query = fmt.Sprintf("insert into %s default values", quoteTable(dialect, table))
+	}
+
+	// 自增字段已有值时不需要写回
+	auto := meta.AutoIncrement()
+	if auto != nil {
+		if value, ok := readFieldByIndex(rv, auto.Index); ok && !value.IsZero() {
+			auto = nil
+		}
+	}
+
+	// 执行之前检查自增字段能否写回，避免插入后才返回错误
+	if auto != nil {
+		if err = checkAutoIncrType(rv.Type().FieldByIndex(auto.Index).Type); err != nil {
+			return nil, err
+		}
+	}
+
+	if auto != nil && dialect == Postgres {
+		query += " returning " + quoteIdent(dialect, auto.Column)
+		var id int64
+		err = conn.QueryRowContext(ctx, query, values...).Scan(&id)
+		logger.Output(query, err, values...)
+		if err != nil {
+			return nil, err
+		}
+
+		return returningResult{id: id}, setIntValue(fieldByIndex(rv, auto.Index), id)
+	}
+
+	result, err := conn.ExecContext(ctx, query, values...)
+	logger.Output(query, err, values...)
+	if err != nil || auto == nil {
+		return result, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return result, err
+	}
+
+	return result, setIntValue(fieldByIndex(rv, auto.Index), id)
+}
+
+// 更新v，where为空时按主键更新
+func update(ctx context.Context, conn sqlConn, logger Logger, dialect, table string, v interface{}, where string, args ...interface{}) (sql.Result, error) {
+	rv, meta, err := writeTarget(v)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := meta.UpdateFields()
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no writable fields in %s", rv.Type())
+	}
+
+	assignments := make([]string, 0, len(fields))
+	values := make([]interface{}, 0, len(fields)+len(args))
+	for i, f := range fields {
+		assignments = append(assignments, quoteIdent(dialect, f.Column)+"="+placeholder(dialect, i+1))
+		values = append(values, f.ValueOf(rv))
+	}
+
+	// 与MySQL一致，where条件的参数排在更新字段之后，PostgreSQL的$n需要依次后移
+	switch {
+	case where == "":
+		where, args, err = primaryKeyCondition(dialect, meta, rv, len(fields))
+		if err != nil {
+			return nil, err
+		}
+	case dialect == Postgres:
+		where = shiftPlaceholders(where, len(fields))
+	}
+	values = append(values, args...)
+
+	query := fmt.Sprintf("update %s set %s where %s", quoteTable(dialect, table), strings.Join(assignments, ","), where)
+	result, err := conn.ExecContext(ctx, query, values...)
+	logger.Output(query, err, values...)
+	return result, err
+}
+
+// 删除记录，where为空时按v的主键删除
+func del(ctx context.Context, conn sqlConn, logger Logger, dialect, table string, v interface{}, where string, args ...interface{}) (sql.Result, error) {
+	if where == "" {
+		if v == nil {
+			return nil, errors.New("delete needs a where condition or a struct with pk fields")
+		}
+
+		rv, meta, err := writeTarget(v)
+		if err != nil {
+			return nil, err
+		}
+
+		where, args, err = primaryKeyCondition(dialect, meta, rv, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := fmt.Sprintf("delete from %s where %s", quoteTable(dialect, table), where)
+	result, err := conn.ExecContext(ctx, query, args...)
+	logger.Output(query, err, args...)
+	return result, err
+}
+
+// 按主键生成where条件，n为已使用的占位符数量
+func primaryKeyCondition(dialect string, meta *Meta, rv reflect.Value, n int) (string, []interface{}, error) {
+	pks := meta.PrimaryKeys()
+	if len(pks) == 0 {
+		return "", nil, fmt.Errorf("no pk fields in %s, a where condition is required", rv.Type())
+	}
+
+	conditions := make([]string, 0, len(pks))
+	args := make([]interface{}, 0, len(pks))
+	for _, f := range pks {
+		n++
+		conditions = append(conditions, quoteIdent(dialect, f.Column)+"="+placeholder(dialect, n))
+		args = append(args, f.ValueOf(rv))
+	}
+
+	return strings.Join(conditions, " and "), args, nil
+}
+
+// 把PostgreSQL语句中的$n改为$(n+offset)，跳过字符串和带引号的标识符
+func shiftPlaceholders(query string, offset int) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(query[i+1 : j])
+			b.WriteString("$" + strconv.Itoa(n+offset))
+			i = j - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// 自增字段需为整数或实现了sql.Scanner的类型，如sql.NullInt64
+func checkAutoIncrType(t reflect.Type) error {
+	t = Deref(t)
+	if reflect.PtrTo(t).Implements(scannerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nil
+	default:
+		return fmt.Errorf("autoincr field of type %s is neither an integer nor a sql.Scanner", t)
+	}
+}
+
+// 写回自增ID，实现了sql.Scanner的类型通过Scan写回
+func setIntValue(v reflect.Value, id int64) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if scanner, ok := v.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(id)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(id))
+	default:
+		return fmt.Errorf("autoincr field of type %s is neither an integer nor a sql.Scanner", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package esql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type writeUser struct {
+	ID        int64   `esql:"id,pk,autoincr"`
+	Name      string  `esql:"name"`
+	Email     *string `esql:"email,omitempty"`
+	CreatedAt string  `esql:"created_at,readonly"`
+}
+
+func TestInsertSQLite(t *testing.T) {
+	db := openSQLite(t, `create table user (
+	id integer primary key autoincrement,
+	name text not null,
+	email text default 'none',
+	created_at text not null default 'now'
+)`)
+	ctx := context.Background()
+
+	tom := writeUser{Name: "tom", CreatedAt: "ignored"}
+	if _, err := db.Insert(ctx, "main.user", &tom); err != nil {
+		t.Fatal(err)
+	}
+	email := "jerry@example.com"
+	jerry := writeUser{Name: "jerry", Email: &email}
+	if _, err := db.Insert(ctx, "user", &jerry); err != nil {
+		t.Fatal(err)
+	}
+	if tom.ID != 1 || jerry.ID != 2 {
+		t.Fatalf("autoincr not set: %d, %d", tom.ID, jerry.ID)
+	}
+
+	// 自增字段已有值时按该值插入
+	fixed := writeUser{ID: 10, Name: "spike"}
+	if _, err := db.Insert(ctx, "user", &fixed); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []writeUser
+	if err := db.QueryRows(&rows, "select * from user order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[2].ID != 10 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	// omitempty的nil字段使用默认值，readonly字段不写入
+	if *rows[0].Email != "none" || rows[0].CreatedAt != "now" || *rows[1].Email != email {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+
+	if _, err := db.Insert(ctx, "user", tom); err == nil {
+		t.Fatal("expected an error for a non-pointer value")
+	}
+}
+
+func TestUpdateDeleteSQLite(t *testing.T) {
+	db := openSQLite(t, `create table user (
+	id integer primary key autoincrement,
+	name text not null,
+	email text,
+	created_at text not null default 'now'
+);
+insert into user (name) values ('tom'), ('jerry'), ('spike');`)
+	ctx := context.Background()
+
+	tom := writeUser{ID: 1, Name: "tommy", CreatedAt: "ignored"}
+	if _, err := db.Update(ctx, "user", &tom, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	jerry := writeUser{Name: "jerry2"}
+	result, err := db.Update(ctx, "user", &jerry, "name = ? or id = ?", "jerry", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := result.RowsAffected(); n != 2 {
+		t.Fatalf("expected 2 rows updated, got %d", n)
+	}
+
+	var names []string
+	if err = db.QueryRows(&names, "select name from user order by id"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "tommy,jerry2,jerry2" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	var created string
+	if err = db.QueryRow(&created, "select created_at from user where id=1"); err != nil || created != "now" {
+		t.Fatalf("readonly field written: %q, %v", created, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Delete(ctx, "user", &tom, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Delete(ctx, "user", nil, "id > ?", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	if err = db.QueryRows(&ids, "select id from user"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int64{2}) {
+		t.Fatalf("unexpected ids: %v", ids)
+	}
+
+	if _, err = db.Delete(ctx, "user", nil, ""); err == nil {
+		t.Fatal("expected an error without where and pk")
+	}
+	type noPK struct {
+		Name string
+	}
+	if _, err = db.Update(ctx, "user", &noPK{}, ""); err == nil {
+		t.Fatal("expected an error without where and pk")
+	}
+}
+
+func TestInsertAutoIncrScanner(t *testing.T) {
+	db := openSQLite(t, `create table user (
+	id integer primary key autoincrement,
+	name text not null
+)`)
+	ctx := context.Background()
+
+	type nullUser struct {
+		ID   sql.NullInt64 `esql:"id,pk,autoincr"`
+		Name string        `esql:"name"`
+	}
+	tom := nullUser{Name: "tom"}
+	if _, err := db.Insert(ctx, "user", &tom); err != nil {
+		t.Fatal(err)
+	}
+	if !tom.ID.Valid || tom.ID.Int64 != 1 {
+		t.Fatalf("autoincr not set: %+v", tom.ID)
+	}
+
+	// 不能写回的类型在插入之前返回错误
+	type stringUser struct {
+		ID   string `esql:"id,pk,autoincr"`
+		Name string `esql:"name"`
+	}
+	if _, err := db.Insert(ctx, "user", &stringUser{Name: "jerry"}); err == nil {
+		t.Fatal("expected an error for a string autoincr field")
+	}
+	var count int
+	if err := db.QueryRow(&count, "select count(*) from user"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("the row should not be inserted: %d rows", count)
+	}
+}
+
+// 记录执行的语句和参数，查询返回一行id为7的结果
+type recordDriver struct {
+	queries *[]string
+	args    *[][]driver.Value
+}
+
+type recordConn struct{ recordDriver }
+
+type recordStmt struct {
+	recordDriver
+	query string
+}
+
+type recordRows struct{ done bool }
+
+func (d recordDriver) Open(string) (driver.Conn, error) { return recordConn{d}, nil }
+
+func (c recordConn) Prepare(query string) (driver.Stmt, error) {
+	return recordStmt{c.recordDriver, query}, nil
+}
+func (recordConn) Close() error              { return nil }
+func (recordConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+func (recordStmt) Close() error  { return nil }
+func (recordStmt) NumInput() int { return -1 }
+
+func (s recordStmt) record(args []driver.Value) {
+	*s.queries = append(*s.queries, s.query)
+	*s.args = append(*s.args, args)
+}
+
+func (s recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.record(args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s recordStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.record(args)
+	return &recordRows{}, nil
+}
+
+func (*recordRows) Columns() []string { return []string{"id"} }
+func (*recordRows) Close() error      { return nil }
+
+func (r *recordRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(7)
+	return nil
+}
+
+var recorder = recordDriver{queries: new([]string), args: new([][]driver.Value)}
+
+func init() {
+	sql.Register("esql-recorder", recorder)
+}
+
+func TestWritePostgresQueries(t *testing.T) {
+	db, err := Open("esql-recorder", "", &logger{level: Disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.DB().Close()
+	db.SetDialect(Postgres)
+	ctx := context.Background()
+
+	u := writeUser{Name: "tom"}
+	result, err := db.Insert(ctx, "app.user", &u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := result.LastInsertId(); u.ID != 7 || id != 7 {
+		t.Fatalf("returning id not set: %d, %d", u.ID, id)
+	}
+	if _, err = db.Update(ctx, "user", &u, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Update(ctx, "user", &u, `name = $1 and note <> '$1' and "$2" = $2`, "tom", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Delete(ctx, "user", &u, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`insert into "app"."user" ("name") values ($1) returning "id"`,
+		`update "user" set "name"=$1,"email"=$2 where "id"=$3`,
+		`update "user" set "name"=$1,"email"=$2 where name = $3 and note <> '$1' and "$2" = $4`,
+		`delete from "user" where "id"=$1`,
+	}
+	if !reflect.DeepEqual(*recorder.queries, want) {
+		t.Fatalf("unexpected queries:\n%s", strings.Join(*recorder.queries, "\n"))
+	}
+
+	wantArgs := [][]driver.Value{
+		{"tom"},
+		{"tom", nil, int64(7)},
+		{"tom", nil, "tom", "x"},
+		{int64(7)},
+	}
+	if !reflect.DeepEqual(*recorder.args, wantArgs) {
+		t.Fatalf("unexpected args: %v", *recorder.args)
+	}
+}
+
+func TestQuoteTable(t *testing.T) {
+	tests := []struct {
+		dialect, table, want string
+	}{
+		{Mysql, "user", "`user`"},
+		{Mysql, "app.user", "`app`.`user`"},
+		{Mysql, "we`ird", "`we``ird`"},
+		{SQLite, "main.user", "`main`.`user`"},
+		{Postgres, "public.user", `"public"."user"`},
+		{Postgres, `we"ird`, `"we""ird"`},
+	}
+	for _, tt := range tests {
+		if got := quoteTable(tt.dialect, tt.table); got != tt.want {
+			t.Errorf("quoteTable(%q, %q) = %s, want %s", tt.dialect, tt.table, got, tt.want)
+		}
+	}
+}